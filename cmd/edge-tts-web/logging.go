@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+)
+
+// newLogger 按级别（debug、info、warn、error）和格式（text、json）创建日志记录器
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q", format)
+}
+
+type loggerKey struct{}
+
+// withRequestLogger 为请求分配 ID（沿用客户端的 X-Request-Id），并把带 request_id 的日志记录器放入 context
+func withRequestLogger(w http.ResponseWriter, r *http.Request) (*http.Request, *slog.Logger) {
+	id := r.Header.Get("X-Request-Id")
+	if id == "" || len(id) > 64 {
+		id = edgetts.ConnectID()
+	}
+	w.Header().Set("X-Request-Id", id)
+	logger := slog.Default().With("request_id", id)
+	return r.WithContext(context.WithValue(r.Context(), loggerKey{}, logger)), logger
+}
+
+// loggerFrom 返回请求的日志记录器
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// logWarnings 记录合成过程中的警告
+func logWarnings(logger *slog.Logger, warnings []error) {
+	for _, warning := range warnings {
+		logger.Warn("synthesis warning", "warning", warning)
+	}
+}
@@ -8,12 +8,15 @@ import (
 	"flag"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"os/exec"
+	"os/signal"
 	"runtime"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
@@ -22,32 +25,91 @@ import (
 //go:embed all:static
 var staticFiles embed.FS
 
-var voicesCache []edgetts.Voice
-var voicesCacheTime time.Time
+// synth 所有请求共用的合成客户端
+var synth *edgetts.Synthesizer
+
+// client 合成和语音列表请求共用的传输层客户端，带有 -identity 等参数指定的客户端标识
+var client = &edgetts.Client{}
+
+// resultCache 合成结果缓存，-cache-size 为 0 时为 nil
+var resultCache *edgetts.MemoryCache
 
 func main() {
 	addr := flag.String("addr", ":8080", "监听地址")
 	openBrowser := flag.Bool("open", false, "启动后自动打开浏览器")
+	cacheSize := flag.Int64("cache-size", 64<<20, "合成结果缓存的音频字节数上限，0 表示不缓存")
+	cacheTTL := flag.Duration("cache-ttl", time.Hour, "合成结果缓存的有效期")
+	logLevel := flag.String("log-level", "info", "日志级别：debug、info、warn 或 error，debug 会输出合成协议事件")
+	logFormat := flag.String("log-format", "text", "日志格式：text 或 json")
+	identityFile := flag.String("identity", "", "客户端标识 JSON 文件（Edge 版本、User-Agent、Origin、TrustedClientToken、MUID 策略），EDGE_TTS_* 环境变量和以下参数依次覆盖")
+	edgeVersion := flag.String("edge-version", "", "模拟的 Edge 完整版本号，例如 "+edgetts.ChromiumFullVersion)
+	userAgent := flag.String("user-agent", "", "请求的 User-Agent，默认按 Edge 版本生成")
+	clientToken := flag.String("trusted-client-token", "", "TrustedClientToken")
+	muid := flag.String("muid", "", "MUID 策略：random、persistent 或 none")
+	voicesFile := flag.String("voices-file", "", "从 JSON 文件读取语音列表，不联网获取")
+	voicesCacheTTL := flag.Duration("voices-cache-ttl", edgetts.DefaultVoiceCacheTTL, "语音列表磁盘缓存的有效期，0 表示不使用磁盘缓存")
 	flag.Parse()
 
+	logger, err := newLogger(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		logger.Error("invalid tracing configuration", "err", err)
+		os.Exit(1)
+	}
+
+	identity, err := edgetts.ResolveClientIdentity(*identityFile, edgetts.ClientIdentity{
+		ChromiumVersion:    *edgeVersion,
+		UserAgent:          *userAgent,
+		TrustedClientToken: *clientToken,
+		MUIDStrategy:       edgetts.MUIDStrategy(*muid),
+	})
+	if err == nil {
+		client.DRM, err = edgetts.NewDRM(identity)
+	}
+	if err != nil {
+		logger.Error("invalid client identity", "err", err)
+		os.Exit(2)
+	}
+
+	opts := []edgetts.CommunicateOption{edgetts.WithClient(client)}
+	if *cacheSize > 0 {
+		resultCache = edgetts.NewMemoryCache(*cacheSize, *cacheTTL)
+		opts = append(opts, edgetts.WithCache(resultCache))
+	}
+	if synth, err = edgetts.NewSynthesizer(opts...); err != nil {
+		logger.Error("invalid synthesizer options", "err", err)
+		os.Exit(1)
+	}
+
 	// 预加载语音列表
+	voices.configure(*voicesFile, *voicesCacheTTL)
 	go preloadVoices()
 
 	// 路由设置
 	mux := http.NewServeMux()
 
 	// API 路由
-	mux.HandleFunc("/api/voices", handleVoices)
-	mux.HandleFunc("/api/voices/", handleVoiceSample)
-	mux.HandleFunc("/api/preview", handlePreview)
-	mux.HandleFunc("/api/synthesize", handleSynthesize)
+	mux.HandleFunc("/api/voices", instrument("/api/voices", handleVoices))
+	mux.HandleFunc("/api/voices/", instrument("/api/voices/sample", handleVoiceSample))
+	mux.HandleFunc("/api/preview", instrument("/api/preview", handlePreview))
+	mux.HandleFunc("/api/synthesize", instrument("/api/synthesize", handleSynthesize))
+	mux.HandleFunc("/api/styles", instrument("/api/styles", handleStyles))
+
+	// Prometheus 指标
+	mux.HandleFunc("/metrics", handleMetrics)
 
 	// 静态文件
 	staticFS, _ := fs.Sub(staticFiles, "static")
 	mux.Handle("/", http.FileServer(http.FS(staticFS)))
 
 	// 启动服务器
-	log.Printf("Edge TTS Web 服务启动: http://localhost%s", *addr)
+	logger.Info("edge-tts-web listening", "url", fmt.Sprintf("http://localhost%s", *addr))
 
 	if *openBrowser {
 		go func() {
@@ -56,11 +118,38 @@ func main() {
 		}()
 	}
 
-	if err := http.ListenAndServe(*addr, mux); err != nil {
-		log.Fatal(err)
+	// 收到 SIGINT 或 SIGTERM 后停止接受新请求，等待进行中的请求结束，再导出剩余的 span
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{Addr: *addr, Handler: mux}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+
+	select {
+	case err := <-serveErr:
+		logger.Error("server stopped", "err", err)
+		shutdownTracing(context.Background())
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+	stop()
+	logger.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("in-flight requests did not finish", "err", err)
+	}
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Warn("failed to flush spans", "err", err)
 	}
 }
 
+// shutdownTimeout 退出时等待进行中的请求结束的最长时间
+const shutdownTimeout = 30 * time.Second
+
 func openURL(url string) {
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
@@ -77,15 +166,9 @@ func openURL(url string) {
 }
 
 func preloadVoices() {
-	ctx := newTimeoutContext()
-	voices, err := edgetts.ListVoices(ctx, nil)
-	if err != nil {
-		log.Printf("预加载语音列表失败: %v", err)
-		return
-	}
-	voicesCache = voices
-	voicesCacheTime = time.Now()
-	log.Printf("已加载 %d 个语音", len(voices))
+	ctx, cancel := newTimeoutContext(context.Background())
+	defer cancel()
+	voices.get(ctx)
 }
 
 // LanguageGroup 语言分组
@@ -282,14 +365,14 @@ func handleVoices(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 使用缓存或重新获取
-	if len(voicesCache) == 0 || time.Since(voicesCacheTime) > 30*time.Minute {
-		preloadVoices()
-	}
+	ctx, cancel := newTimeoutContext(r.Context())
+	defer cancel()
+	list := voices.get(ctx)
 
 	// 按语言分组
 	groups := make(map[string]*LanguageGroup)
 
-	for _, v := range voicesCache {
+	for _, v := range list {
 		locale := v.Locale
 
 		if _, exists := groups[locale]; !exists {
@@ -393,19 +476,29 @@ func handleVoiceSample(w http.ResponseWriter, r *http.Request) {
 	// 根据语言选择示例文本
 	sampleText := getSampleText(voiceID)
 
-	ctx := newTimeoutContext()
-	comm, err := edgetts.NewCommunicate(sampleText, voiceID)
+	ctx, cancel := newTimeoutContext(r.Context())
+	defer cancel()
+	logger := loggerFrom(r.Context())
+	obs := metrics.observe(voiceID, sampleText)
+	comm, err := synth.NewCommunicate(sampleText, edgetts.WithVoice(voiceID), edgetts.WithLogger(logger), obs.option())
 	if err != nil {
+		obs.done(err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "audio/mpeg")
-	w.Header().Set("Cache-Control", "public, max-age=3600")
-
-	if err := comm.StreamToWriter(ctx, w, nil); err != nil {
-		log.Printf("语音合成错误: %v", err)
+	// 示例音频经常被重复请求，合成完整结果以便使用缓存
+	result, err := comm.Synthesize(ctx)
+	obs.synthesized(result, err)
+	if err != nil {
+		logger.Error("voice sample synthesis failed", "voice", voiceID, "err", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
 	}
+
+	w.Header().Set("Content-Type", result.Format.MIMEType())
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	result.WriteTo(w)
 }
 
 func getSampleText(voiceID string) string {
@@ -435,10 +528,14 @@ func getSampleText(voiceID string) string {
 
 // PreviewRequest 预览请求
 type PreviewRequest struct {
-	Text   string `json:"text"`
-	Voice  string `json:"voice"`
-	Rate   string `json:"rate"`
-	Pitch  string `json:"pitch"`
+	Text        string  `json:"text"`
+	Voice       string  `json:"voice"`
+	Rate        string  `json:"rate"`
+	Pitch       string  `json:"pitch"`
+	Format      string  `json:"format"`
+	Style       string  `json:"style"`
+	StyleDegree float64 `json:"styleDegree"`
+	Role        string  `json:"role"`
 }
 
 func handlePreview(w http.ResponseWriter, r *http.Request) {
@@ -458,42 +555,68 @@ func handlePreview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Voice == "" {
-		req.Voice = edgetts.DefaultVoice
-	}
-	if req.Rate == "" {
-		req.Rate = "+0%"
-	}
-	if req.Pitch == "" {
-		req.Pitch = "+0Hz"
+	opts, err := req.options()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	ctx := newTimeoutContext()
-	comm, err := edgetts.NewCommunicate(
-		req.Text,
-		req.Voice,
-		edgetts.WithRate(req.Rate),
-		edgetts.WithPitch(req.Pitch),
-	)
+	ctx, cancel := newTimeoutContext(r.Context())
+	defer cancel()
+	logger := loggerFrom(r.Context())
+	obs := metrics.observe(req.Voice, req.Text)
+	comm, err := synth.NewCommunicate(req.Text, append(opts, edgetts.WithLogger(logger), obs.option())...)
 	if err != nil {
+		obs.done(err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "audio/mpeg")
+	w.Header().Set("Content-Type", comm.OutputFormat().MIMEType())
+
+	err = comm.StreamToWriter(ctx, w, nil)
+	obs.done(err)
+	if err != nil {
+		logger.Error("preview synthesis failed", "err", err)
+	}
+	logWarnings(logger, comm.Warnings())
+}
 
-	if err := comm.StreamToWriter(ctx, w, nil); err != nil {
-		log.Printf("预览合成错误: %v", err)
+// options 把请求参数转换为覆盖默认设置的选项，空字段使用 Synthesizer 的默认值
+func (req *PreviewRequest) options() ([]edgetts.CommunicateOption, error) {
+	opts := []edgetts.CommunicateOption{
+		edgetts.WithStyle(req.Style),
+		edgetts.WithStyleDegree(req.StyleDegree),
+		edgetts.WithRole(req.Role),
+	}
+	// 已加载的语音列表带有风格信息时，按所选语音校验风格和角色
+	if snap := voices.current.Load(); snap != nil && (req.Style != "" || req.Role != "") {
+		opts = append(opts, edgetts.WithVoiceMetadata(snap.voices...))
+	}
+	if req.Voice != "" {
+		opts = append(opts, edgetts.WithVoice(req.Voice))
+	}
+	if req.Rate != "" {
+		opts = append(opts, edgetts.WithRate(req.Rate))
+	}
+	if req.Pitch != "" {
+		opts = append(opts, edgetts.WithPitch(req.Pitch))
 	}
+	if req.Format != "" {
+		format, err := edgetts.ParseOutputFormat(req.Format)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, edgetts.WithOutputFormat(format))
+	}
+	return opts, nil
 }
 
 // SynthesizeRequest 合成请求
 type SynthesizeRequest struct {
-	Text        string `json:"text"`
-	Voice       string `json:"voice"`
-	Rate        string `json:"rate"`
-	Pitch       string `json:"pitch"`
+	PreviewRequest
 	WithSRT     bool   `json:"withSrt"`
+	InputFormat string `json:"inputFormat"`
 }
 
 func handleSynthesize(w http.ResponseWriter, r *http.Request) {
@@ -513,88 +636,73 @@ func handleSynthesize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Voice == "" {
-		req.Voice = edgetts.DefaultVoice
-	}
-	if req.Rate == "" {
-		req.Rate = "+0%"
-	}
-	if req.Pitch == "" {
-		req.Pitch = "+0Hz"
+	opts, err := req.options()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	opts = append(opts, edgetts.WithInputFormat(edgetts.InputFormat(req.InputFormat)))
 
-	ctx := newTimeoutContext()
-	comm, err := edgetts.NewCommunicate(
-		req.Text,
-		req.Voice,
-		edgetts.WithRate(req.Rate),
-		edgetts.WithPitch(req.Pitch),
-	)
+	ctx, cancel := newTimeoutContext(r.Context())
+	defer cancel()
+	logger := loggerFrom(r.Context())
+	obs := metrics.observe(req.Voice, req.Text)
+	comm, err := synth.NewCommunicate(req.Text, append(opts, edgetts.WithLogger(logger), obs.option())...)
 	if err != nil {
+		obs.done(err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	result, err := comm.Synthesize(ctx)
+	obs.synthesized(result, err)
+	if err != nil {
+		logger.Error("synthesis failed", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logWarnings(logger, result.Warnings)
+
 	if req.WithSRT {
 		// 返回 JSON，包含音频的 base64 和 SRT
-		handleSynthesizeWithSRT(w, ctx, comm)
-	} else {
-		// 直接返回音频流
-		filename := fmt.Sprintf("tts_%d.mp3", time.Now().Unix())
-		w.Header().Set("Content-Type", "audio/mpeg")
-		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
-
-		if err := comm.StreamToWriter(ctx, w, nil); err != nil {
-			log.Printf("合成错误: %v", err)
-		}
+		writeSynthesisJSON(w, result)
+		return
 	}
-}
 
-func handleSynthesizeWithSRT(w http.ResponseWriter, ctx contextWithTimeout, comm *edgetts.Communicate) {
-	submaker := edgetts.NewSubMaker()
-
-	// 收集音频数据
-	var audioData []byte
-
-	chunkCh, errCh := comm.Stream(ctx)
+	// 直接返回音频
+	filename := fmt.Sprintf("tts_%d%s", time.Now().Unix(), result.Format.Extension())
+	w.Header().Set("Content-Type", result.Format.MIMEType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	result.WriteTo(w)
+}
 
-	for {
-		select {
-		case chunk, ok := <-chunkCh:
-			if !ok {
-				goto done
-			}
-			if chunk.Type == "audio" {
-				audioData = append(audioData, chunk.Data...)
-			} else if chunk.Type == "WordBoundary" || chunk.Type == "SentenceBoundary" {
-				submaker.Feed(chunk)
-			}
-		case err := <-errCh:
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-		case <-ctx.Done():
-			http.Error(w, "Timeout", http.StatusRequestTimeout)
-			return
-		}
+// writeSynthesisJSON 返回包含 base64 音频、SRT 字幕和警告的 JSON
+func writeSynthesisJSON(w http.ResponseWriter, result *edgetts.SynthesisResult) {
+	warnings := []string{}
+	for _, warning := range result.Warnings {
+		warnings = append(warnings, warning.Error())
 	}
 
-done:
-	// 编码为 base64
-	audioBase64 := base64.StdEncoding.EncodeToString(audioData)
-	srtContent := submaker.GetSRT()
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"audio": audioBase64,
-		"srt":   srtContent,
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"audio":     base64.StdEncoding.EncodeToString(result.Audio),
+		"srt":       result.SRT(),
+		"mimeType":  result.Format.MIMEType(),
+		"extension": result.Format.Extension(),
+		"warnings":  warnings,
 	})
 }
 
-type contextWithTimeout = context.Context
+func handleStyles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(map[string][]string{
+		"styles": edgetts.KnownStyles,
+		"roles":  edgetts.KnownRoles,
+	})
+}
 
-func newTimeoutContext() context.Context {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Minute)
-	return ctx
+// newTimeoutContext 返回带超时的 context，客户端断开时 parent 被取消，合成随之停止
+func newTimeoutContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, 5*time.Minute)
 }
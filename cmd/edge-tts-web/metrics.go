@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+)
+
+// latencyBuckets 合成耗时直方图的桶上限（秒）
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// serverMetrics 服务的运行指标，以 Prometheus 文本格式在 /metrics 输出
+type serverMetrics struct {
+	requests       *counterVec
+	firstAudio     *histogram
+	duration       *histogram
+	characters     *counterVec
+	upstreamErrors *counterVec
+	retries        *counterVec
+	resultCache    *counterVec
+	voiceList      *counterVec
+	inFlight       atomic.Int64
+}
+
+var metrics = &serverMetrics{
+	requests: newCounterVec("edgetts_http_requests_total",
+		"HTTP requests by handler and status code.", "handler", "code"),
+	firstAudio: newHistogram("edgetts_synthesis_first_audio_seconds",
+		"Time from the start of an upstream synthesis to the first audio byte.", latencyBuckets),
+	duration: newHistogram("edgetts_synthesis_duration_seconds",
+		"Total time of successful upstream syntheses.", latencyBuckets),
+	characters: newCounterVec("edgetts_synthesized_characters_total",
+		"Characters synthesized upstream by voice.", "voice"),
+	upstreamErrors: newCounterVec("edgetts_upstream_errors_total",
+		"Failed upstream syntheses by error class.", "class"),
+	retries: newCounterVec("edgetts_upstream_retries_total",
+		"Upstream handshake retries and mid-turn reconnects.", "reason"),
+	resultCache: newCounterVec("edgetts_result_cache_requests_total",
+		"Synthesis result cache lookups by result.", "result"),
+	voiceList: newCounterVec("edgetts_voice_list_requests_total",
+		"Voice list requests by result: hit, miss or error.", "result"),
+}
+
+// handleMetrics 输出 Prometheus 文本格式的指标
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.writeTo(w)
+}
+
+func (m *serverMetrics) writeTo(w io.Writer) {
+	m.requests.writeTo(w)
+	m.firstAudio.writeTo(w)
+	m.duration.writeTo(w)
+	m.characters.writeTo(w)
+	m.upstreamErrors.writeTo(w)
+	m.retries.writeTo(w)
+	m.resultCache.writeTo(w)
+	m.voiceList.writeTo(w)
+
+	writeGauge(w, "edgetts_synthesis_in_flight", "Upstream syntheses in progress.", float64(m.inFlight.Load()))
+	if age, ok := voices.age(); ok {
+		writeGauge(w, "edgetts_voice_list_age_seconds", "Age of the cached voice list.", age.Seconds())
+	}
+	if resultCache != nil {
+		writeGauge(w, "edgetts_result_cache_entries", "Entries in the synthesis result cache.", float64(resultCache.Len()))
+	}
+}
+
+// instrument 记录处理函数的请求数和状态码，分配请求 ID，开始 server span 并输出访问日志
+func instrument(handler string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, logger := withRequestLogger(w, r)
+		r, span := startServerSpan(r, handler)
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		h(sw, r)
+		endServerSpan(span, sw.code)
+		metrics.requests.inc(handler, strconv.Itoa(sw.code))
+		logger.Info("request", "method", r.Method, "path", r.URL.Path, "status", sw.code, "duration", time.Since(start))
+	}
+}
+
+// statusWriter 记录响应状态码
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// synthesisObserver 记录一次合成的指标
+type synthesisObserver struct {
+	voice      string
+	characters int
+	start      time.Time
+	firstAudio sync.Once
+}
+
+// observe 开始记录一次合成，调用方必须调用 done 或 synthesized
+func (m *serverMetrics) observe(voice, text string) *synthesisObserver {
+	if voice == "" {
+		voice = edgetts.DefaultVoice
+	}
+	m.inFlight.Add(1)
+	return &synthesisObserver{voice: voice, characters: utf8.RuneCountInString(text), start: time.Now()}
+}
+
+// option 返回记录首包延迟、重试和上游错误的回调选项
+func (o *synthesisObserver) option() edgetts.CommunicateOption {
+	return edgetts.WithHooks(edgetts.Hooks{
+		OnAudio: func(edgetts.TurnInfo, []byte) {
+			o.firstAudio.Do(func() {
+				metrics.firstAudio.observe(time.Since(o.start).Seconds())
+			})
+		},
+		OnRetry: func(r edgetts.RetryInfo) {
+			reason := "handshake"
+			if r.Reconnect {
+				reason = "reconnect"
+			}
+			metrics.retries.inc(reason)
+		},
+		OnError: func(err error) {
+			if class := errorClass(err); class != "" {
+				metrics.upstreamErrors.inc(class)
+			}
+		},
+	})
+}
+
+// done 合成结束，成功时记录耗时和字符数
+func (o *synthesisObserver) done(err error) {
+	metrics.inFlight.Add(-1)
+	if err == nil {
+		metrics.duration.observe(time.Since(o.start).Seconds())
+		metrics.characters.add(float64(o.characters), o.voice)
+	}
+}
+
+// synthesized Synthesize 结束，缓存命中不计入合成耗时和字符数
+func (o *synthesisObserver) synthesized(result *edgetts.SynthesisResult, err error) {
+	if err == nil && result.Cached {
+		metrics.inFlight.Add(-1)
+		metrics.resultCache.inc("hit")
+		return
+	}
+	if err == nil && resultCache != nil {
+		metrics.resultCache.inc("miss")
+	}
+	o.done(err)
+}
+
+// errorClass 上游错误的分类，客户端断开不算上游错误，返回空字符串
+func errorClass(err error) string {
+	var he *edgetts.HandshakeError
+	var pe *edgetts.ProtocolError
+	var ne net.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ""
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &ne) && ne.Timeout():
+		return "timeout"
+	case errors.As(err, &he):
+		return "handshake"
+	case errors.As(err, &pe):
+		return "protocol"
+	case errors.Is(err, edgetts.ErrWebSocket), errors.As(err, &opErr), errors.As(err, &dnsErr):
+		return "connection"
+	}
+	return "other"
+}
+
+// counterVec 带标签的计数器
+type counterVec struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64 // 键为按顺序拼接的标签值
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+}
+
+func (c *counterVec) inc(labelValues ...string) {
+	c.add(1, labelValues...)
+}
+
+func (c *counterVec) add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+func (c *counterVec) writeTo(w io.Writer) {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]float64, len(keys))
+	for i, key := range keys {
+		values[i] = c.values[key]
+	}
+	c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for i, key := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, strings.Split(key, "\xff")), formatValue(values[i]))
+	}
+}
+
+// histogram 不带标签的直方图
+type histogram struct {
+	name, help string
+	buckets    []float64
+
+	mu     sync.Mutex
+	counts []uint64 // 落在每个桶内（不累计）的观测数，最后一个为 +Inf
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(name, help string, buckets []float64) *histogram {
+	return &histogram{name: name, help: help, buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+func (h *histogram) writeTo(w io.Writer) {
+	h.mu.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	sum, count := h.sum, h.count
+	h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatValue(upper), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, count)
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", h.name, formatValue(sum), h.name, count)
+}
+
+func writeGauge(w io.Writer, name, help string, v float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatValue(v))
+}
+
+// formatLabels 格式化标签，转义反斜杠、双引号和换行
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		var v string
+		if i < len(values) {
+			v = values[i]
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(v))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
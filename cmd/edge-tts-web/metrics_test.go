@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+)
+
+func TestCounterVecOutput(t *testing.T) {
+	c := newCounterVec("test_requests_total", "Test requests.", "handler", "code")
+	c.inc("/b", "200")
+	c.add(2, "/a", "500")
+	c.inc("/a", "500")
+	c.inc("say \"hi\"\\\nbye", "200")
+
+	var b strings.Builder
+	c.writeTo(&b)
+	want := `# HELP test_requests_total Test requests.
+# TYPE test_requests_total counter
+test_requests_total{handler="/a",code="500"} 3
+test_requests_total{handler="/b",code="200"} 1
+test_requests_total{handler="say \"hi\"\\\nbye",code="200"} 1
+`
+	if got := b.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogramOutput(t *testing.T) {
+	h := newHistogram("test_seconds", "Test latency.", []float64{0.1, 0.5, 1})
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.7, 2, 5} {
+		h.observe(v)
+	}
+
+	var b strings.Builder
+	h.writeTo(&b)
+	// 桶按上界累计，等于上界的观测落在该桶内，超过最大上界的只计入 +Inf
+	want := `# HELP test_seconds Test latency.
+# TYPE test_seconds histogram
+test_seconds_bucket{le="0.1"} 2
+test_seconds_bucket{le="0.5"} 3
+test_seconds_bucket{le="1"} 4
+test_seconds_bucket{le="+Inf"} 6
+test_seconds_sum 8.15
+test_seconds_count 6
+`
+	if got := b.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteGauge(t *testing.T) {
+	var b strings.Builder
+	writeGauge(&b, "test_in_flight", "In flight.", 3)
+	if got, want := b.String(), "# HELP test_in_flight In flight.\n# TYPE test_in_flight gauge\ntest_in_flight 3\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+// timeoutError 超时的 net.Error
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{context.Canceled, ""},
+		{fmt.Errorf("stream: %w", context.Canceled), ""},
+		{context.DeadlineExceeded, "timeout"},
+		{&net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, "timeout"},
+		{&edgetts.HandshakeError{Op: "websocket handshake", StatusCode: 403}, "handshake"},
+		{fmt.Errorf("chunk 2: %w", &edgetts.ProtocolError{Path: "audio", Err: edgetts.ErrUnexpectedResponse}), "protocol"},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, "connection"},
+		{&net.DNSError{Err: "no such host", Name: "speech.platform.bing.com"}, "connection"},
+		{edgetts.ErrNoAudioReceived, "other"},
+	}
+	for _, tt := range tests {
+		if got := errorClass(tt.err); got != tt.want {
+			t.Errorf("errorClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -23,6 +23,8 @@
         rateValue: document.getElementById('rate-value'),
         pitchSlider: document.getElementById('pitch-slider'),
         pitchValue: document.getElementById('pitch-value'),
+        styleSelect: document.getElementById('style-select'),
+        roleSelect: document.getElementById('role-select'),
         srtCheckbox: document.getElementById('srt-checkbox'),
         previewBtn: document.getElementById('preview-btn'),
         downloadBtn: document.getElementById('download-btn'),
@@ -38,6 +40,7 @@
     async function init() {
         loadHistory();
         await loadVoices();
+        await loadStyles();
         bindEvents();
     }
 
@@ -54,6 +57,21 @@
         }
     }
 
+    // 加载说话风格和角色
+    async function loadStyles() {
+        try {
+            const response = await fetch('/api/styles');
+            const data = await response.json();
+            const toOptions = items => (items || []).map(item =>
+                `<option value="${item}">${item}</option>`
+            ).join('');
+            elements.styleSelect.innerHTML += toOptions(data.styles);
+            elements.roleSelect.innerHTML += toOptions(data.roles);
+        } catch (error) {
+            console.error('Failed to load styles:', error);
+        }
+    }
+
     // 渲染语言选项
     function renderLanguageOptions() {
         const options = state.languages.map(lang =>
@@ -203,6 +221,8 @@
             voice: elements.voiceSelect.value,
             rate: rate === 0 ? '+0%' : (rate > 0 ? `+${rate}%` : `${rate}%`),
             pitch: pitch === 0 ? '+0Hz' : (pitch > 0 ? `+${pitch}Hz` : `${pitch}Hz`),
+            style: elements.styleSelect.value,
+            role: elements.roleSelect.value,
             withSrt: elements.srtCheckbox.checked
         };
     }
@@ -232,7 +252,9 @@
                     text: params.text,
                     voice: params.voice,
                     rate: params.rate,
-                    pitch: params.pitch
+                    pitch: params.pitch,
+                    style: params.style,
+                    role: params.role
                 })
             });
 
@@ -302,6 +324,8 @@
                     voice: params.voice,
                     rate: params.rate,
                     pitch: params.pitch,
+                    style: params.style,
+                    role: params.role,
                     withSrt: params.withSrt
                 })
             });
@@ -315,10 +339,11 @@
             if (params.withSrt) {
                 // 返回 JSON，包含 base64 音频和 SRT
                 const data = await response.json();
+                (data.warnings || []).forEach(warning => console.warn(warning));
 
                 // 下载音频
-                const audioBlob = base64ToBlob(data.audio, 'audio/mpeg');
-                downloadBlob(audioBlob, `tts_${timestamp}.mp3`);
+                const audioBlob = base64ToBlob(data.audio, data.mimeType || 'audio/mpeg');
+                downloadBlob(audioBlob, `tts_${timestamp}${data.extension || '.mp3'}`);
 
                 // 下载字幕
                 if (data.srt) {
@@ -328,7 +353,7 @@
             } else {
                 // 直接下载音频
                 const blob = await response.blob();
-                downloadBlob(blob, `tts_${timestamp}.mp3`);
+                downloadBlob(blob, `tts_${timestamp}${dispositionExtension(response) || '.mp3'}`);
             }
 
             // 添加到历史记录
@@ -349,6 +374,13 @@
         }
     }
 
+    // 从 Content-Disposition 的文件名中取出扩展名（包含点），服务端按输出格式设置
+    function dispositionExtension(response) {
+        const disposition = response.headers.get('Content-Disposition') || '';
+        const match = disposition.match(/filename="?[^";]*(\.[A-Za-z0-9]+)"?/);
+        return match ? match[1] : '';
+    }
+
     // Base64 转 Blob
     function base64ToBlob(base64, mimeType) {
         const byteString = atob(base64);
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupTracing 配置 W3C Trace Context 传播；设置了 OTEL_EXPORTER_OTLP_ENDPOINT 或
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT 时通过 OTLP/HTTP 导出 span（需要用 -tags otlp 编译），
+// 其余配置同样读取 OTEL_* 环境变量
+// 返回的 shutdown 在退出前导出剩余的 span
+func setupTracing(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	return setupExporter(ctx)
+}
+
+// startServerSpan 从请求头提取调用方的 trace context，开始处理请求的 server span
+func startServerSpan(r *http.Request, handler string) (*http.Request, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := otel.Tracer("edge-tts-web").Start(ctx, r.Method+" "+handler,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", handler),
+			attribute.String("url.path", r.URL.Path),
+		))
+	return r.WithContext(ctx), span
+}
+
+// endServerSpan 记录响应状态码并结束 server span，5xx 标记为错误
+func endServerSpan(span trace.Span, code int) {
+	span.SetAttributes(attribute.Int("http.response.status_code", code))
+	if code >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(code))
+	}
+	span.End()
+}
@@ -0,0 +1,14 @@
+//go:build !otlp
+
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+// setupExporter 没有使用 -tags otlp 编译时不导出 span，只传播 trace context
+func setupExporter(context.Context) (shutdown func(context.Context) error, err error) {
+	slog.Warn("OTEL_EXPORTER_OTLP_ENDPOINT is set but the binary was built without -tags otlp; spans are not exported")
+	return func(context.Context) error { return nil }, nil
+}
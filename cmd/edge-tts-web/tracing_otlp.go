@@ -0,0 +1,32 @@
+//go:build otlp
+
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// setupExporter 通过 OTLP/HTTP 导出 span，配置读取 OTEL_* 环境变量
+func setupExporter(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	exp, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME 和 OTEL_RESOURCE_ATTRIBUTES 覆盖默认的服务名
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "edge-tts-web")),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+)
+
+// voiceRefresh 内存中的语音列表的刷新间隔
+const voiceRefresh = 30 * time.Minute
+
+// voiceSnapshot 一次加载得到的语音列表
+type voiceSnapshot struct {
+	voices  []edgetts.Voice
+	loaded  time.Time // 加载到内存的时间
+	updated time.Time // 列表从服务端获取的时间，快照为零值
+}
+
+// voiceStore 并发安全的语音列表：读取不加锁，过期后由一个请求刷新，其余请求等待刷新结果
+type voiceStore struct {
+	mu      sync.Mutex // 串行化刷新
+	vm      *edgetts.VoicesManager
+	current atomic.Pointer[voiceSnapshot]
+}
+
+// voices 语音列表，由 main 按 -voices-file 和 -voices-cache-ttl 配置
+var voices = &voiceStore{vm: edgetts.NewVoicesManager()}
+
+// configure 设置语音列表的来源：file 不为空时只读取该文件；cacheTTL 为 0 时不使用磁盘缓存
+func (s *voiceStore) configure(file string, cacheTTL time.Duration) {
+	s.vm.ListOptions = &edgetts.ListVoicesOptions{Client: client, Logger: slog.Default()}
+	s.vm.VoicesFile = file
+	s.vm.SnapshotFallback = true
+	if cacheTTL > 0 {
+		s.vm.Cache = &edgetts.VoiceCache{TTL: cacheTTL}
+	}
+}
+
+// get 返回语音列表，尚未加载或超过 voiceRefresh 时重新加载
+// 重新加载失败时继续使用内存中的列表
+func (s *voiceStore) get(ctx context.Context) []edgetts.Voice {
+	if snap := s.fresh(); snap != nil {
+		metrics.voiceList.inc("hit")
+		return snap.voices
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 等待锁期间其他请求可能已经完成刷新
+	if snap := s.fresh(); snap != nil {
+		metrics.voiceList.inc("hit")
+		return snap.voices
+	}
+	metrics.voiceList.inc("miss")
+	if err := s.vm.Create(ctx, nil); err != nil {
+		metrics.voiceList.inc("error")
+		slog.Error("failed to load voice list", "err", err)
+		if snap := s.current.Load(); snap != nil {
+			return snap.voices
+		}
+		return nil
+	}
+	s.current.Store(&voiceSnapshot{voices: s.vm.Voices, loaded: time.Now(), updated: s.vm.UpdatedAt})
+	slog.Info("loaded voice list", "voices", len(s.vm.Voices), "source", s.vm.Source)
+	return s.vm.Voices
+}
+
+// fresh 返回未过期的语音列表，没有时返回 nil
+func (s *voiceStore) fresh() *voiceSnapshot {
+	snap := s.current.Load()
+	if snap == nil || len(snap.voices) == 0 || time.Since(snap.loaded) > voiceRefresh {
+		return nil
+	}
+	return snap
+}
+
+// age 返回语音列表的数据年龄，来源没有获取时间时按加载时间计算；尚未加载时返回 false
+func (s *voiceStore) age() (time.Duration, bool) {
+	snap := s.current.Load()
+	if snap == nil {
+		return 0, false
+	}
+	if !snap.updated.IsZero() {
+		return time.Since(snap.updated), true
+	}
+	return time.Since(snap.loaded), true
+}
@@ -2,24 +2,68 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
 )
 
 const version = "1.0.0"
 
-func printVoices(ctx context.Context, proxy string) error {
-	voices, err := edgetts.ListVoices(ctx, &edgetts.ListVoicesOptions{Proxy: proxy})
-	if err != nil {
+// logger 日志和错误输出到 stderr，级别和格式由 --log-level 和 --log-format 设置
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+// client 传输层客户端，带有 --identity 等参数指定的客户端标识
+var client = &edgetts.Client{}
+
+// newLogger 按级别（debug、info、warn、error）和格式（text、json）创建日志记录器
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q", format)
+}
+
+// fatal 记录错误并退出
+func fatal(msg string, err error) {
+	logger.Error(msg, "err", err)
+	os.Exit(1)
+}
+
+// printVoices 列出语音：使用 voicesFile 或磁盘缓存（cacheTTL 为 0 时不缓存），联网失败时使用内置快照
+func printVoices(ctx context.Context, proxy, voicesFile string, cacheTTL time.Duration) error {
+	vm := edgetts.NewVoicesManager()
+	vm.ListOptions = &edgetts.ListVoicesOptions{Proxy: proxy, Client: client, Logger: logger}
+	vm.VoicesFile = voicesFile
+	vm.SnapshotFallback = true
+	if cacheTTL > 0 {
+		vm.Cache = &edgetts.VoiceCache{TTL: cacheTTL}
+	}
+	if err := vm.Create(ctx, nil); err != nil {
 		return err
 	}
+	voices := vm.Voices
+	logger.Info("loaded voice list", "source", vm.Source, "voices", len(voices))
+	if vm.Source == edgetts.VoiceSourceSnapshot {
+		fmt.Fprintf(os.Stderr, "Note: offline, listing %d voices from the built-in partial snapshot; voices not listed can still be used with -v\n", len(voices))
+	}
 
 	// 按 ShortName 排序
 	sort.Slice(voices, func(i, j int) bool {
@@ -38,15 +82,96 @@ func printVoices(ctx context.Context, proxy string) error {
 	return w.Flush()
 }
 
-func runTTS(ctx context.Context, text, voice, rate, volume, pitch, proxy, writeMedia, writeSubtitles string) error {
-	comm, err := edgetts.NewCommunicate(
-		text,
-		voice,
+// resolveOutputFormat 确定输出格式：优先使用显式指定的格式，否则根据输出文件扩展名推断
+func resolveOutputFormat(format, writeMedia string) (edgetts.OutputFormat, error) {
+	if format != "" {
+		return edgetts.ParseOutputFormat(format)
+	}
+	if writeMedia != "" && writeMedia != "-" {
+		if f, ok := edgetts.OutputFormatForExtension(filepath.Ext(writeMedia)); ok {
+			return f, nil
+		}
+	}
+	return edgetts.DefaultOutputFormat, nil
+}
+
+// communicateFunc 按输入类型创建通信实例
+type communicateFunc func(opts ...edgetts.CommunicateOption) (*edgetts.Communicate, error)
+
+// newCommunicateFunc 根据输入类型返回创建通信实例的函数
+// dialogue 输入为 JSON 数组，每项包含 speaker、voice、rate、volume、pitch 和 text
+func newCommunicateFunc(text, voice string, ssml, dialogue bool, gap time.Duration) (communicateFunc, error) {
+	switch {
+	case ssml && dialogue:
+		return nil, fmt.Errorf("--ssml and --dialogue are mutually exclusive")
+	case ssml:
+		return func(opts ...edgetts.CommunicateOption) (*edgetts.Communicate, error) {
+			return edgetts.NewCommunicateSSML(text, voice, opts...)
+		}, nil
+	case dialogue:
+		d := edgetts.Dialogue{Gap: gap}
+		if err := json.Unmarshal([]byte(text), &d.Turns); err != nil {
+			return nil, fmt.Errorf("invalid dialogue: %w", err)
+		}
+		return func(opts ...edgetts.CommunicateOption) (*edgetts.Communicate, error) {
+			return edgetts.NewCommunicateDialogue(d, append(opts, edgetts.WithVoice(voice))...)
+		}, nil
+	default:
+		return func(opts ...edgetts.CommunicateOption) (*edgetts.Communicate, error) {
+			return edgetts.NewCommunicate(text, voice, opts...)
+		}, nil
+	}
+}
+
+// resolveInputFormat 确定输入格式：优先使用显式指定的格式，否则根据输入文件扩展名推断
+func resolveInputFormat(format, inputFile string) (edgetts.InputFormat, error) {
+	if format != "" {
+		return edgetts.ParseInputFormat(format)
+	}
+	if inputFile != "" && inputFile != "-" {
+		if f, ok := edgetts.InputFormatForExtension(filepath.Ext(inputFile)); ok {
+			return f, nil
+		}
+	}
+	return edgetts.InputText, nil
+}
+
+// parseTextProcessors 按逗号分隔的名称查找已注册的文本处理器
+func parseTextProcessors(names string) ([]edgetts.TextProcessor, error) {
+	var procs []edgetts.TextProcessor
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p, ok := edgetts.LookupTextProcessor(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown text processor %q (available: %s)", name, strings.Join(edgetts.TextProcessorNames(), ", "))
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+func runTTS(ctx context.Context, newComm communicateFunc, rate, volume, pitch, proxy, format, writeMedia, writeSubtitles string, concurrency int, extra ...edgetts.CommunicateOption) error {
+	outputFormat, err := resolveOutputFormat(format, writeMedia)
+	if err != nil {
+		return err
+	}
+
+	opts := []edgetts.CommunicateOption{
 		edgetts.WithRate(rate),
 		edgetts.WithVolume(volume),
 		edgetts.WithPitch(pitch),
 		edgetts.WithProxy(proxy),
-	)
+		edgetts.WithOutputFormat(outputFormat),
+		edgetts.WithConcurrency(concurrency),
+		edgetts.WithLogger(logger),
+		edgetts.WithClient(client),
+	}
+	opts = append(opts, extra...)
+
+	comm, err := newComm(opts...)
 	if err != nil {
 		return err
 	}
@@ -72,10 +197,16 @@ func runTTS(ctx context.Context, text, voice, rate, volume, pitch, proxy, writeM
 	if err := comm.StreamToWriter(ctx, audioWriter, submaker); err != nil {
 		return err
 	}
+	for _, warning := range comm.Warnings() {
+		logger.Warn("synthesis warning", "warning", warning)
+	}
 
-	// 写入字幕
+	// 写入字幕，.vtt 扩展名输出 WebVTT，其余输出 SRT
 	if writeSubtitles != "" {
 		srt := submaker.GetSRT()
+		if strings.EqualFold(filepath.Ext(writeSubtitles), ".vtt") {
+			srt = submaker.GetVTT()
+		}
 		if writeSubtitles == "-" {
 			fmt.Fprint(os.Stderr, srt)
 		} else {
@@ -102,25 +233,86 @@ func main() {
 	volume := flag.String("volume", "+0%", "Speech volume")
 	pitch := flag.String("pitch", "+0Hz", "Speech pitch")
 	writeMedia := flag.String("write-media", "", "Output audio file")
+	outputFormat := flag.String("output-format", "", "Audio output format (default: inferred from --write-media extension, else "+string(edgetts.DefaultOutputFormat)+")")
+	listFormats := flag.Bool("list-formats", false, "List available audio output formats")
 	writeSubtitles := flag.String("write-subtitles", "", "Output subtitles file")
-	proxy := flag.String("proxy", "", "Proxy URL")
+	normalize := flag.String("normalize", "", "Comma-separated text processors applied before synthesis ("+strings.Join(edgetts.TextProcessorNames(), ", ")+")")
+	chunkSize := flag.Int("chunk-size", edgetts.DefaultChunkSize, "Maximum bytes of text per synthesis request")
+	concurrency := flag.Int("concurrency", 1, "Number of connections used to synthesize long text in parallel")
+	style := flag.String("style", "", "Speaking style, e.g. cheerful, sad (see --list-styles)")
+	styleDegree := flag.Float64("style-degree", 0, "Speaking style intensity, 0.01-2 (default: voice default)")
+	role := flag.String("role", "", "Role play, e.g. Girl, SeniorMale (see --list-styles)")
+	listStyles := flag.Bool("list-styles", false, "List known speaking styles and roles")
+	dialogue := flag.Bool("dialogue", false, "Treat the input as a JSON dialogue: [{\"speaker\":\"Alice\",\"voice\":\"en-US-EmmaMultilingualNeural\",\"text\":\"Hi\"}, ...]")
+	dialogueGap := flag.Duration("dialogue-gap", 300*time.Millisecond, "Pause between dialogue turns")
+	ssml := flag.Bool("ssml", false, "Treat the input text as SSML (a full <speak> document or a fragment)")
+	inputFormat := flag.String("input-format", "", "Input format: markdown, html or text (default: inferred from -f extension, else text)")
+	codeBlocks := flag.String("code-blocks", "skip", "How to read code blocks in markdown/html input: skip, announce or read")
+	emphasizeHeadings := flag.Bool("emphasize-headings", false, "Read headings in markdown/html input with emphasis")
+	proxy := flag.String("proxy", "", "Proxy URL (http://[user:pass@]host:port or socks5://[user:pass@]host:port; default: HTTP_PROXY/HTTPS_PROXY/NO_PROXY)")
 	showVersion := flag.Bool("version", false, "Show version")
+	logLevel := flag.String("log-level", "warn", "Log level: debug, info, warn or error (debug logs protocol events)")
+	logFormat := flag.String("log-format", "text", "Log format: text or json")
+	identityFile := flag.String("identity", "", "Client identity JSON file (Edge version, user agent, origin, trusted client token, MUID strategy); EDGE_TTS_* variables and the flags below override it")
+	edgeVersion := flag.String("edge-version", "", "Full Edge version to present, e.g. "+edgetts.ChromiumFullVersion)
+	userAgent := flag.String("user-agent", "", "User-Agent header (default: derived from the Edge version)")
+	clientToken := flag.String("trusted-client-token", "", "TrustedClientToken")
+	muid := flag.String("muid", "", "MUID strategy: random, persistent or none")
+	voicesFile := flag.String("voices-file", "", "Read the voice list from a JSON file (same format as the service) instead of the network")
+	voicesCacheTTL := flag.Duration("voices-cache-ttl", edgetts.DefaultVoiceCacheTTL, "How long the voice list cached under $XDG_CACHE_HOME/edge-tts is used before revalidating (0 disables the cache)")
 
 	flag.Parse()
 
+	var err error
+	if logger, err = newLogger(os.Stderr, *logLevel, *logFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	identity, err := edgetts.ResolveClientIdentity(*identityFile, edgetts.ClientIdentity{
+		ChromiumVersion:    *edgeVersion,
+		UserAgent:          *userAgent,
+		TrustedClientToken: *clientToken,
+		MUIDStrategy:       edgetts.MUIDStrategy(*muid),
+	})
+	if err == nil {
+		client.DRM, err = edgetts.NewDRM(identity)
+	}
+	if err != nil {
+		fatal("invalid client identity", err)
+	}
+
 	// 处理版本
 	if *showVersion {
 		fmt.Printf("edge-tts-go %s\n", version)
 		return
 	}
 
+	// 处理列出输出格式
+	if *listFormats {
+		for _, f := range edgetts.OutputFormats() {
+			fmt.Printf("%s\t%s\n", f, f.MIMEType())
+		}
+		return
+	}
+
+	if *listStyles {
+		fmt.Println("Styles:")
+		for _, s := range edgetts.KnownStyles {
+			fmt.Printf("  %s\n", s)
+		}
+		fmt.Println("Roles:")
+		for _, r := range edgetts.KnownRoles {
+			fmt.Printf("  %s\n", r)
+		}
+		return
+	}
+
 	ctx := context.Background()
 
 	// 处理列出语音
 	if *listVoices || *listVoicesAlias {
-		if err := printVoices(ctx, *proxy); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		if err := printVoices(ctx, *proxy, *voicesFile, *voicesCacheTTL); err != nil {
+			fatal("failed to list voices", err)
 		}
 		return
 	}
@@ -141,22 +333,20 @@ func main() {
 		if inputFile == "-" || inputFile == "/dev/stdin" {
 			data, err := io.ReadAll(os.Stdin)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
-				os.Exit(1)
+				fatal("failed to read stdin", err)
 			}
 			inputText = string(data)
 		} else {
 			data, err := os.ReadFile(inputFile)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
-				os.Exit(1)
+				fatal("failed to read file", err)
 			}
 			inputText = string(data)
 		}
 	}
 
 	if inputText == "" {
-		fmt.Fprintln(os.Stderr, "Error: no text provided. Use -t or -f to specify text.")
+		logger.Error("no text provided, use -t or -f to specify text")
 		flag.Usage()
 		os.Exit(1)
 	}
@@ -168,8 +358,33 @@ func main() {
 	}
 
 	// 运行 TTS
-	if err := runTTS(ctx, inputText, selectedVoice, *rate, *volume, *pitch, *proxy, *writeMedia, *writeSubtitles); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	procs, err := parseTextProcessors(*normalize)
+	if err != nil {
+		fatal("invalid --normalize", err)
+	}
+	format := edgetts.InputText
+	switch {
+	case (*ssml || *dialogue) && *inputFormat != "":
+		err = fmt.Errorf("--input-format cannot be combined with --ssml or --dialogue")
+	case !*ssml && !*dialogue:
+		format, err = resolveInputFormat(*inputFormat, inputFile)
+	}
+	if err != nil {
+		fatal("invalid input format", err)
+	}
+	docOpts := edgetts.DefaultDocumentOptions
+	docOpts.EmphasizeHeadings = *emphasizeHeadings
+	if docOpts.CodeBlocks, err = edgetts.ParseCodeBlockMode(*codeBlocks); err != nil {
+		fatal("invalid --code-blocks", err)
+	}
+	newComm, err := newCommunicateFunc(inputText, selectedVoice, *ssml, *dialogue, *dialogueGap)
+	if err != nil {
+		fatal("invalid input", err)
+	}
+	if err := runTTS(ctx, newComm, *rate, *volume, *pitch, *proxy, *outputFormat, *writeMedia, *writeSubtitles, *concurrency,
+		edgetts.WithStyle(*style), edgetts.WithStyleDegree(*styleDegree), edgetts.WithRole(*role),
+		edgetts.WithChunkSize(*chunkSize), edgetts.WithTextProcessors(procs...),
+		edgetts.WithInputFormat(format), edgetts.WithDocumentOptions(docOpts)); err != nil {
+		fatal("synthesis failed", err)
 	}
 }
@@ -0,0 +1,101 @@
+package edgetts
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache 合成结果缓存，实现必须可以被并发使用
+type Cache interface {
+	Get(key string) (*SynthesisResult, bool)
+	Add(key string, r *SynthesisResult)
+}
+
+// WithCache 设置合成结果缓存，供 Synthesize 使用，Stream 等流式接口不使用缓存；传入 nil 关闭缓存
+func WithCache(cache Cache) CommunicateOption {
+	return func(c *Communicate) {
+		c.cache = cache
+	}
+}
+
+// MemoryCache 按音频字节数限制容量的 LRU 内存缓存
+type MemoryCache struct {
+	maxBytes int64
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List // 最近使用的在前
+	entries map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key     string
+	result  *SynthesisResult
+	expires time.Time
+}
+
+// NewMemoryCache 创建内存缓存，maxBytes 为音频总字节数上限，ttl 为 0 表示不过期
+func NewMemoryCache(maxBytes int64, ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+// Get 实现 Cache
+func (m *MemoryCache) Get(key string) (*SynthesisResult, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*memoryCacheEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		m.remove(el)
+		return nil, false
+	}
+	m.order.MoveToFront(el)
+	return e.result, true
+}
+
+// Add 实现 Cache，超过容量的结果不会被缓存
+func (m *MemoryCache) Add(key string, r *SynthesisResult) {
+	size := int64(len(r.Audio))
+	if size > m.maxBytes {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+	e := &memoryCacheEntry{key: key, result: r}
+	if m.ttl > 0 {
+		e.expires = time.Now().Add(m.ttl)
+	}
+	m.entries[key] = m.order.PushFront(e)
+	m.size += size
+	for m.size > m.maxBytes {
+		m.remove(m.order.Back())
+	}
+}
+
+// Len 返回缓存的结果数
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// remove 删除一项，调用方需持有锁
+func (m *MemoryCache) remove(el *list.Element) {
+	e := m.order.Remove(el).(*memoryCacheEntry)
+	delete(m.entries, e.key)
+	m.size -= int64(len(e.result.Audio))
+}
@@ -0,0 +1,212 @@
+package edgetts
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Client 传输层配置，可按实例覆盖服务地址、HTTP 客户端、拨号器、TLS 配置和请求头
+// 零值可用，未设置的字段使用包级默认值
+type Client struct {
+	// WSSURL WebSocket 合成地址，默认为 WSSURL
+	WSSURL string
+	// VoiceListURL 语音列表地址，默认为 VoiceList
+	VoiceListURL string
+	// HTTPClient 语音列表请求使用的 HTTP 客户端
+	// 设置后 Dialer、TLSConfig 和 Proxy 对语音列表请求不再生效
+	HTTPClient *http.Client
+	// Dialer 建立 TCP 连接使用的拨号器
+	Dialer *net.Dialer
+	// TLSConfig TLS 配置（自定义 CA、客户端证书等）
+	TLSConfig *tls.Config
+	// Header 额外的请求头，同名时覆盖默认值
+	Header http.Header
+	// Proxy 代理地址，格式同 WithProxy；WithProxy 设置的值优先
+	Proxy string
+	// DRM 客户端标识和时钟偏移，默认为 GetDRM() 返回的全局实例
+	DRM *DRM
+
+	mu         sync.Mutex
+	transports map[string]*http.Transport // 按代理地址复用的语音列表 transport
+}
+
+// DefaultClient 默认客户端
+var DefaultClient = &Client{}
+
+// WithClient 设置传输层客户端
+func WithClient(client *Client) CommunicateOption {
+	return func(c *Communicate) {
+		c.client = client
+	}
+}
+
+// orDefault 返回非 nil 的客户端
+func (cl *Client) orDefault() *Client {
+	if cl == nil {
+		return DefaultClient
+	}
+	return cl
+}
+
+// effectiveProxy 返回实际使用的代理地址
+func (cl *Client) effectiveProxy(proxy string) string {
+	if proxy != "" {
+		return proxy
+	}
+	return cl.Proxy
+}
+
+// drm 返回生成鉴权参数使用的 DRM 实例
+func (cl *Client) drm() *DRM {
+	if cl.DRM == nil {
+		return GetDRM()
+	}
+	return cl.DRM
+}
+
+// webSocketURL 构建带鉴权参数的 WebSocket 地址
+// 默认地址使用客户端标识的 TrustedClientToken，自定义地址中已有的 TrustedClientToken 保持不变
+func (cl *Client) webSocketURL(connectionID string) (string, error) {
+	return cl.authURL(cl.WSSURL, WSSURL, "TrustedClientToken", map[string]string{"ConnectionId": connectionID})
+}
+
+// voiceListURL 构建带鉴权参数的语音列表地址
+func (cl *Client) voiceListURL() (string, error) {
+	return cl.authURL(cl.VoiceListURL, VoiceList, "trustedclienttoken", nil)
+}
+
+// authURL 在地址上追加 Sec-MS-GEC、Sec-MS-GEC-Version、TrustedClientToken 和 params
+func (cl *Client) authURL(base, defaultBase, tokenParam string, params map[string]string) (string, error) {
+	drm := cl.drm()
+	id := drm.Identity()
+	query := map[string]string{
+		"Sec-MS-GEC":         drm.GenerateSecMSGEC(),
+		"Sec-MS-GEC-Version": id.secMSGECVersion(),
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+	if base == "" {
+		base = defaultBase
+		query[tokenParam] = id.TrustedClientToken
+	} else if u, err := url.Parse(base); err == nil && !u.Query().Has(tokenParam) {
+		query[tokenParam] = id.TrustedClientToken
+	}
+	return withQuery(base, query)
+}
+
+// withQuery 在地址上追加查询参数
+func withQuery(rawURL string, params map[string]string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", rawURL, err)
+	}
+	q := u.Query()
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// mergeHeaders 合并默认 headers、客户端标识、MUID cookie 和额外 headers
+func (cl *Client) mergeHeaders(defaults map[string]string, identity func(http.Header, ClientIdentity)) http.Header {
+	drm := cl.drm()
+	headers := http.Header{}
+	for k, v := range defaults {
+		headers.Set(k, v)
+	}
+	identity(headers, drm.Identity())
+	if cookie := drm.cookie(); cookie != "" {
+		headers.Set("Cookie", cookie)
+	}
+	for k, v := range cl.Header {
+		headers[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+	return headers
+}
+
+// webSocketHeaders 返回 WebSocket 握手 headers
+func (cl *Client) webSocketHeaders() http.Header {
+	return cl.mergeHeaders(WSSHeaders, func(h http.Header, id ClientIdentity) {
+		h.Set("User-Agent", id.UserAgent)
+		h.Set("Origin", id.Origin)
+	})
+}
+
+// voiceListHeaders 返回语音列表请求 headers
+func (cl *Client) voiceListHeaders() http.Header {
+	return cl.mergeHeaders(VoiceHeaders, func(h http.Header, id ClientIdentity) {
+		h.Set("User-Agent", id.UserAgent)
+		h.Set("Sec-CH-UA", secCHUA(id.majorVersion()))
+	})
+}
+
+// webSocketDialer 创建 WebSocket dialer
+func (cl *Client) webSocketDialer(proxy string, handshakeTimeout time.Duration) (*websocket.Dialer, error) {
+	dialer, err := newWebSocketDialer(cl.effectiveProxy(proxy), handshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if cl.Dialer != nil {
+		dialer.NetDialContext = cl.Dialer.DialContext
+	}
+	if cl.TLSConfig != nil {
+		dialer.TLSClientConfig = cl.TLSConfig.Clone()
+	}
+	return dialer, nil
+}
+
+// httpClient 返回语音列表请求使用的 HTTP 客户端
+// 同一代理地址的请求共用一个 transport 和其中的空闲连接
+func (cl *Client) httpClient(proxy string, timeout time.Duration) (*http.Client, error) {
+	if cl.HTTPClient != nil {
+		return cl.HTTPClient, nil
+	}
+	transport, err := cl.httpTransport(cl.effectiveProxy(proxy))
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// httpTransport 返回 proxy 对应的 transport，首次使用时创建
+// 创建后再修改 Dialer 和 TLSConfig 不影响已有的 transport
+func (cl *Client) httpTransport(proxy string) (*http.Transport, error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if transport, ok := cl.transports[proxy]; ok {
+		return transport, nil
+	}
+	transport, err := newHTTPTransport(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if cl.Dialer != nil {
+		transport.DialContext = cl.Dialer.DialContext
+	}
+	if cl.TLSConfig != nil {
+		transport.TLSClientConfig = cl.TLSConfig.Clone()
+	}
+	if cl.transports == nil {
+		cl.transports = map[string]*http.Transport{}
+	}
+	cl.transports[proxy] = transport
+	return transport, nil
+}
+
+// CloseIdleConnections 关闭语音列表请求的空闲连接
+func (cl *Client) CloseIdleConnections() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	for _, transport := range cl.transports {
+		transport.CloseIdleConnections()
+	}
+}
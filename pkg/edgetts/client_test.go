@@ -0,0 +1,134 @@
+package edgetts
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func tlsConfigFor(srv *httptest.Server) *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return &tls.Config{RootCAs: pool}
+}
+
+func TestListVoicesCustomClient(t *testing.T) {
+	var gotHeader, gotGEC string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Relay-Tenant")
+		gotGEC = r.URL.Query().Get("Sec-MS-GEC")
+		json.NewEncoder(w).Encode([]Voice{{ShortName: "en-US-TestNeural", Locale: "en-US"}})
+	}))
+	defer srv.Close()
+
+	client := &Client{
+		VoiceListURL: srv.URL + "/voices/list?trustedclienttoken=abc",
+		Dialer:       &net.Dialer{Timeout: time.Second},
+		TLSConfig:    tlsConfigFor(srv),
+		Header:       http.Header{"X-Relay-Tenant": {"team-a"}},
+	}
+
+	voices, err := ListVoices(context.Background(), &ListVoicesOptions{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(voices) != 1 || voices[0].ShortName != "en-US-TestNeural" {
+		t.Fatalf("voices = %+v", voices)
+	}
+	if gotHeader != "team-a" {
+		t.Errorf("X-Relay-Tenant = %q, want team-a", gotHeader)
+	}
+	if gotGEC == "" {
+		t.Error("Sec-MS-GEC query parameter missing")
+	}
+}
+
+func TestListVoicesReusesConnections(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]Voice{{ShortName: "en-US-TestNeural", Locale: "en-US"}})
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	client := &Client{VoiceListURL: srv.URL, TLSConfig: tlsConfigFor(srv)}
+	defer client.CloseIdleConnections()
+	for i := 0; i < 3; i++ {
+		if _, err := ListVoices(context.Background(), &ListVoicesOptions{Client: client, Timeout: 5 * time.Second}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if conns != 1 {
+		t.Errorf("opened %d connections, want 1", conns)
+	}
+}
+
+func TestListVoicesUntrustedCertificate(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	_, err := ListVoices(context.Background(), &ListVoicesOptions{
+		Client: &Client{VoiceListURL: srv.URL},
+	})
+	if err == nil {
+		t.Fatal("expected certificate verification error")
+	}
+}
+
+func TestCommunicateCustomWebSocketEndpoint(t *testing.T) {
+	handshake := make(chan *http.Request, 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handshake <- r
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	client := &Client{
+		WSSURL:    "wss" + strings.TrimPrefix(srv.URL, "https") + "/edge/v1?TrustedClientToken=abc",
+		TLSConfig: tlsConfigFor(srv),
+		Header:    http.Header{"Origin": {"https://relay.example"}},
+	}
+	comm, err := NewCommunicate("hello", "", WithClient(client), WithRetry(NoRetry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comm.StreamSync(context.Background()); err == nil {
+		t.Fatal("expected handshake error")
+	}
+
+	r := <-handshake
+	if r.URL.Path != "/edge/v1" {
+		t.Errorf("path = %q, want /edge/v1", r.URL.Path)
+	}
+	q := r.URL.Query()
+	if q.Get("TrustedClientToken") != "abc" || q.Get("ConnectionId") == "" || q.Get("Sec-MS-GEC") == "" {
+		t.Errorf("query = %v", q)
+	}
+	if got := r.Header.Get("Origin"); got != "https://relay.example" {
+		t.Errorf("Origin = %q, want override", got)
+	}
+}
@@ -2,15 +2,17 @@ package edgetts
 
 import (
 	"context"
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"log/slog"
 	"os"
+	"sync"
 	"time"
+	"unicode/utf8"
 
-	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // CommunicateOption 通信选项
@@ -37,7 +39,15 @@ func WithPitch(pitch string) CommunicateOption {
 	}
 }
 
-// WithProxy 设置代理
+// WithVoice 设置语音，会覆盖构造函数中的 voice 参数；对话中未指定语音的句子使用该语音
+func WithVoice(voice string) CommunicateOption {
+	return func(c *Communicate) {
+		c.ttsConfig.Voice = voice
+	}
+}
+
+// WithProxy 设置代理，支持 http://[user:pass@]host:port 和 socks5://[user:pass@]host:port
+// 未设置时读取 HTTP_PROXY/HTTPS_PROXY/NO_PROXY 环境变量
 func WithProxy(proxy string) CommunicateOption {
 	return func(c *Communicate) {
 		c.proxy = proxy
@@ -65,18 +75,85 @@ func WithBoundary(boundary string) CommunicateOption {
 	}
 }
 
-// Communicate 与 TTS 服务通信
+// WithOutputFormat 设置音频输出格式
+func WithOutputFormat(format OutputFormat) CommunicateOption {
+	return func(c *Communicate) {
+		c.ttsConfig.Format = format
+	}
+}
+
+// Communicate 与 TTS 服务通信，每个实例对应一段输入，只能合成一次
+// 需要复用配置或并发合成时使用 Synthesizer
 type Communicate struct {
-	ttsConfig      *TTSConfig
-	texts          [][]byte
-	proxy          string
-	connectTimeout time.Duration
-	receiveTimeout time.Duration
-	state          *CommunicateState
+	ttsConfig       *TTSConfig
+	texts           [][]byte
+	proxy           string
+	client          *Client
+	retry           RetryPolicy
+	connectTimeout  time.Duration
+	receiveTimeout  time.Duration
+	keepAlive       time.Duration
+	concurrency     int
+	rawSSML         bool     // texts 是完整的 SSML 文档
+	userSSML        bool     // texts 来自调用方提供的 SSML，不修改其中的说话风格
+	speakers        []string // 每个文本块的说话人，仅用于对话
+	splitter        Splitter
+	chunkSize       int
+	processors      []TextProcessor
+	inputFormat     InputFormat
+	documentOptions DocumentOptions
+	voiceMetadata   []Voice // 校验说话风格使用的语音元数据
+	cache           Cache
+	hooks           Hooks
+	logger          *slog.Logger
+	tracerProvider  trace.TracerProvider
+	tracer          trace.Tracer
+	characters      int            // 输入的字符数，记录在 span 上
+	tracker         *sourceTracker // 把边界定位回处理前的文本
+	spans           [][2]int       // 每个文本块在转义后文本中的字节范围，仅纯文本输入
+	state           *CommunicateState
+
+	mu           sync.Mutex
+	styleDropped bool
+	warnings     []error
+	closed       bool
+	cancel       context.CancelFunc // 取消正在进行的合成
+	done         chan struct{}      // 合成的后台 goroutine 退出时关闭
 }
 
 // NewCommunicate 创建新的通信实例
 func NewCommunicate(text string, voice string, opts ...CommunicateOption) (*Communicate, error) {
+	c, err := newCommunicate(voice, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.characters = utf8.RuneCountInString(text)
+
+	// 处理文本：移除不兼容字符，执行文本处理链，转义，按字节分割
+	cleanText := RemoveIncompatibleCharacters(text)
+	if c.inputFormat == InputMarkdown || c.inputFormat == InputHTML {
+		if err := c.setDocument(cleanText); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	processed, offsets, err := ProcessText(cleanText, c.processors...)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.processors) > 0 {
+		c.tracker = &sourceTracker{source: cleanText, processed: processed, offsets: offsets}
+	}
+	escapedText := EscapeXML(processed)
+	c.texts = c.splitter.Split(escapedText, c.chunkSize)
+	c.spans = chunkSpans(escapedText, c.texts)
+
+	return c, nil
+}
+
+// newCommunicate 创建通信实例并应用、校验选项，不处理输入文本
+func newCommunicate(voice string, opts ...CommunicateOption) (*Communicate, error) {
 	if voice == "" {
 		voice = DefaultVoice
 	}
@@ -88,9 +165,16 @@ func NewCommunicate(text string, voice string, opts ...CommunicateOption) (*Comm
 			Volume:   "+0%",
 			Pitch:    "+0Hz",
 			Boundary: "SentenceBoundary",
+			Format:   DefaultOutputFormat,
 		},
-		connectTimeout: 10 * time.Second,
-		receiveTimeout: 60 * time.Second,
+		retry:           DefaultRetryPolicy,
+		connectTimeout:  10 * time.Second,
+		receiveTimeout:  60 * time.Second,
+		keepAlive:       DefaultKeepAlive,
+		splitter:        DefaultSplitter,
+		chunkSize:       DefaultChunkSize,
+		inputFormat:     InputText,
+		documentOptions: DefaultDocumentOptions,
 		state: &CommunicateState{
 			PartialText:        nil,
 			OffsetCompensation: 0,
@@ -103,285 +187,290 @@ func NewCommunicate(text string, voice string, opts ...CommunicateOption) (*Comm
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.hooks = c.hooks.withDefaults()
+	c.logger = loggerOrDiscard(c.logger)
+	c.tracer = tracerFrom(c.tracerProvider)
 
 	// 验证配置
 	if err := ValidateTTSConfig(c.ttsConfig); err != nil {
 		return nil, err
 	}
-
-	// 处理文本：移除不兼容字符，转义，按字节分割
-	cleanText := RemoveIncompatibleCharacters(text)
-	escapedText := EscapeXML(cleanText)
-	c.texts = SplitTextByByteLength(escapedText, 4096)
-
-	return c, nil
-}
-
-// parseMetadata 解析元数据
-func (c *Communicate) parseMetadata(data []byte) (*TTSChunk, error) {
-	var resp MetadataResponse
-	if err := json.Unmarshal(data, &resp); err != nil {
+	if err := validateVoiceStyle(c.ttsConfig, c.voiceMetadata); err != nil {
 		return nil, err
 	}
-
-	for _, meta := range resp.Metadata {
-		if meta.Type == "WordBoundary" || meta.Type == "SentenceBoundary" {
-			currentOffset := meta.Data.Offset + c.state.OffsetCompensation
-			return &TTSChunk{
-				Type:     meta.Type,
-				Offset:   currentOffset,
-				Duration: meta.Data.Duration,
-				Text:     UnescapeXML(meta.Data.Text.Text),
-			}, nil
-		}
-		if meta.Type == "SessionEnd" {
-			continue
-		}
-		return nil, fmt.Errorf("%w: unknown metadata type: %s", ErrUnknownResponse, meta.Type)
+	if c.chunkSize <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidChunkSize, c.chunkSize)
 	}
-
-	return nil, fmt.Errorf("%w: no WordBoundary metadata found", ErrUnexpectedResponse)
+	if c.splitter == nil {
+		c.splitter = DefaultSplitter
+	}
+	format, err := ParseInputFormat(string(c.inputFormat))
+	if err != nil {
+		return nil, err
+	}
+	c.inputFormat = format
+	if err := c.documentOptions.validate(); err != nil {
+		return nil, err
+	}
+	c.client = c.client.orDefault()
+	if _, err := parseProxyURL(c.client.effectiveProxy(c.proxy)); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-// stream 内部流处理
-func (c *Communicate) stream(ctx context.Context) (<-chan TTSChunk, <-chan error) {
-	chunkCh := make(chan TTSChunk, 100)
-	errCh := make(chan error, 1)
-
-	go func() {
-		defer close(chunkCh)
-		defer close(errCh)
-
-		drm := GetDRM()
+// OutputFormat 返回音频输出格式
+func (c *Communicate) OutputFormat() OutputFormat {
+	return c.ttsConfig.Format
+}
 
-		// 构建 WebSocket URL
-		wsURL := fmt.Sprintf("%s&ConnectionId=%s&Sec-MS-GEC=%s&Sec-MS-GEC-Version=%s",
-			WSSURL, ConnectID(), drm.GenerateSecMSGEC(), SecMSGECVersion)
+// Warnings 返回合成过程中产生的警告，例如服务端不支持说话风格时的 ErrStyleDropped
+func (c *Communicate) Warnings() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]error(nil), c.warnings...)
+}
 
-		// 设置 WebSocket headers
-		headers := http.Header{}
-		for k, v := range HeadersWithMUID(WSSHeaders) {
-			headers.Set(k, v)
+// ssml 返回文本块对应的 SSML 文档
+func (c *Communicate) ssml(text []byte) string {
+	c.mu.Lock()
+	dropped := c.styleDropped
+	c.mu.Unlock()
+	if c.rawSSML {
+		// Markdown、HTML 和对话由我们渲染，去掉风格时移除渲染时加上的 mstts:express-as
+		if dropped && !c.userSSML {
+			if plain, err := withoutExpressAs(string(text)); err == nil {
+				return plain
+			}
 		}
+		return string(text)
+	}
+	if dropped {
+		return MKSSML(c.ttsConfig.withoutStyle(), string(text))
+	}
+	return MKSSML(c.ttsConfig, string(text))
+}
 
-		// 连接 WebSocket
-		dialer := websocket.Dialer{
-			HandshakeTimeout: c.connectTimeout,
-		}
+// dropStyle 服务端因说话风格没有返回音频时，去掉风格并记录警告
+func (c *Communicate) dropStyle(err error) bool {
+	if c.userSSML || !c.ttsConfig.hasStyle() || !errors.Is(err, ErrNoAudioReceived) {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.styleDropped {
+		c.logger.Warn("speaking style not supported, synthesizing without it", "style", c.ttsConfig.Style, "role", c.ttsConfig.Role)
+		c.styleDropped = true
+		c.warnings = append(c.warnings, fmt.Errorf("%w: style %q, role %q", ErrStyleDropped, c.ttsConfig.Style, c.ttsConfig.Role))
+	}
+	return true
+}
 
-		conn, _, err := dialer.DialContext(ctx, wsURL, headers)
-		if err != nil {
-			errCh <- fmt.Errorf("websocket dial error: %w", err)
-			return
-		}
-		defer conn.Close()
-
-		// 设置读取超时
-		conn.SetReadDeadline(time.Now().Add(c.receiveTimeout))
-
-		// 发送配置请求
-		wordBoundary := c.ttsConfig.Boundary == "WordBoundary"
-		wd := "false"
-		sq := "true"
-		if wordBoundary {
-			wd = "true"
-			sq = "false"
+// synthTurn 合成第 i 个文本块，说话风格不被支持时去掉风格重试一次
+func (c *Communicate) synthTurn(ctx context.Context, conn **ttsConn, i int, emit func(TTSChunk) error, discard func()) (err error) {
+	ctx, span := c.tracer.Start(ctx, "edgetts.chunk", trace.WithAttributes(attrChunk.Int(i)))
+	audioBytes := 0
+	defer func() { endSpan(span, err, attrAudioBytes.Int(audioBytes)) }()
+	emit = func(emit func(TTSChunk) error) func(TTSChunk) error {
+		return func(chunk TTSChunk) error {
+			audioBytes += len(chunk.Data)
+			return emit(chunk)
 		}
+	}(emit)
 
-		configMsg := fmt.Sprintf("X-Timestamp:%s\r\n"+
-			"Content-Type:application/json; charset=utf-8\r\n"+
-			"Path:speech.config\r\n\r\n"+
-			`{"context":{"synthesis":{"audio":{"metadataoptions":`+
-			`{"sentenceBoundaryEnabled":"%s","wordBoundaryEnabled":"%s"},`+
-			`"outputFormat":"audio-24khz-48kbitrate-mono-mp3"}}}}`+"\r\n",
-			DateToString(), sq, wd)
-
-		if err := conn.WriteMessage(websocket.TextMessage, []byte(configMsg)); err != nil {
-			errCh <- fmt.Errorf("write config error: %w", err)
-			return
+	ssml := c.ssml(c.texts[i])
+	err = c.runTurn(ctx, conn, i, ssml, emit, discard)
+	if err != nil && c.dropStyle(err) {
+		if plain := c.ssml(c.texts[i]); plain != ssml {
+			err = c.runTurn(ctx, conn, i, plain, emit, discard)
 		}
+	}
+	return err
+}
 
-		// 发送 SSML 请求
-		ssml := MKSSML(c.ttsConfig, string(c.state.PartialText))
-		ssmlMsg := SSMLHeadersPlusData(ConnectID(), DateToString(), ssml)
+// chunkError 把第 i 个文本块的错误包装为 ChunkError
+func (c *Communicate) chunkError(i int, requestID string, err error) *ChunkError {
+	ce := &ChunkError{Index: i, Start: -1, End: -1, RequestID: requestID, Err: err}
+	if i < len(c.spans) {
+		ce.Start, ce.End = c.spans[i][0], c.spans[i][1]
+	}
+	return ce
+}
 
-		if err := conn.WriteMessage(websocket.TextMessage, []byte(ssmlMsg)); err != nil {
-			errCh <- fmt.Errorf("write ssml error: %w", err)
-			return
+// compensate 为第 i 个文本块的边界添加跨回合的偏移补偿、说话人和源文本位置
+func (c *Communicate) compensate(i int, chunk TTSChunk) TTSChunk {
+	if chunk.Type == "WordBoundary" || chunk.Type == "SentenceBoundary" {
+		chunk.Offset += c.state.OffsetCompensation
+		c.state.LastDurationOffset = chunk.Offset + chunk.Duration
+		if i < len(c.speakers) {
+			chunk.Speaker = c.speakers[i]
+		}
+		if c.tracker != nil {
+			c.tracker.locate(&chunk)
 		}
+	}
+	return chunk
+}
 
-		audioReceived := false
+// endTurn 回合结束，更新下一回合的偏移补偿
+func (c *Communicate) endTurn() {
+	c.state.OffsetCompensation = c.state.LastDurationOffset + turnPadding
+}
 
-		// 读取响应
-		for {
-			select {
-			case <-ctx.Done():
-				errCh <- ctx.Err()
-				return
-			default:
-			}
+// runTurn 在 *conn 上执行第 i 个文本块的回合，必要时建立新连接
+// 连接断开且回合尚未产生数据时自动重连重试；discard 不为空时表示调用方缓存了数据，
+// 回合中途断开也可以丢弃已收到的数据后重试
+// 合成失败时返回 ChunkError，ctx 取消和 emit 返回的错误原样返回
+func (c *Communicate) runTurn(ctx context.Context, conn **ttsConn, i int, ssml string, emit func(TTSChunk) error, discard func()) error {
+	var requestID string
+	fail := func(err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return c.chunkError(i, requestID, err)
+	}
 
-			conn.SetReadDeadline(time.Now().Add(c.receiveTimeout))
-			msgType, data, err := conn.ReadMessage()
+	for reconnects := 0; ; reconnects++ {
+		if *conn == nil {
+			tc, err := c.openConn(ctx)
 			if err != nil {
-				if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
-					break
-				}
-				errCh <- fmt.Errorf("read message error: %w", err)
-				return
+				return fail(err)
 			}
+			*conn = tc
+		}
 
-			if msgType == websocket.TextMessage {
-				// 找到 header 和 data 的分隔点
-				headerEnd := -1
-				for i := 0; i < len(data)-3; i++ {
-					if data[i] == '\r' && data[i+1] == '\n' && data[i+2] == '\r' && data[i+3] == '\n' {
-						headerEnd = i
-						break
-					}
-				}
-
-				if headerEnd < 0 {
-					continue
-				}
-
-				headers, body := GetHeadersAndData(data, headerEnd)
-				path := headers["Path"]
-
-				switch path {
-				case "audio.metadata":
-					parsed, err := c.parseMetadata(body)
-					if err != nil {
-						errCh <- err
-						return
-					}
-					chunkCh <- *parsed
-					c.state.LastDurationOffset = parsed.Offset + parsed.Duration
-
-				case "turn.end":
-					c.state.OffsetCompensation = c.state.LastDurationOffset
-					c.state.OffsetCompensation += 8_750_000
-					goto done
-
-				case "response", "turn.start":
-					// 忽略
-
-				default:
-					errCh <- fmt.Errorf("%w: unknown path: %s", ErrUnknownResponse, path)
-					return
-				}
-
-			} else if msgType == websocket.BinaryMessage {
-				if len(data) < 2 {
-					errCh <- fmt.Errorf("%w: binary message missing header length", ErrUnexpectedResponse)
-					return
-				}
-
-				headerLength := int(binary.BigEndian.Uint16(data[:2]))
-				if headerLength+2 > len(data) {
-					errCh <- fmt.Errorf("%w: header length > data length", ErrUnexpectedResponse)
-					return
-				}
-
-				// 跳过前 2 字节（长度），解析 headers 和 body
-				headers, body := GetHeadersAndData(data[2:], headerLength)
-
-				if headers["Path"] != "audio" {
-					errCh <- fmt.Errorf("%w: binary message path is not audio", ErrUnexpectedResponse)
-					return
-				}
-
-				contentType := headers["Content-Type"]
-				if contentType != "audio/mpeg" && contentType != "" {
-					errCh <- fmt.Errorf("%w: unexpected content type: %s", ErrUnexpectedResponse, contentType)
-					return
-				}
-
-				if contentType == "" {
-					if len(body) == 0 {
-						continue
-					}
-					errCh <- fmt.Errorf("%w: no content type but has data", ErrUnexpectedResponse)
-					return
-				}
-
-				if len(body) == 0 {
-					errCh <- fmt.Errorf("%w: audio content type but no data", ErrUnexpectedResponse)
-					return
-				}
+		requestID = ConnectID()
+		trace.SpanFromContext(ctx).SetAttributes(attrRequestID.String(requestID))
+		info := TurnInfo{Chunk: i, Chunks: len(c.texts), RequestID: requestID, SSML: ssml}
+		emitted := false
+		var emitErr error
+		err := (*conn).turn(ctx, info, func(chunk TTSChunk) error {
+			emitted = true
+			emitErr = emit(chunk)
+			return emitErr
+		})
+		if err == nil {
+			return nil
+		}
 
-				audioReceived = true
-				chunkCh <- TTSChunk{
-					Type: "audio",
-					Data: body,
-				}
+		(*conn).Close()
+		*conn = nil
+		if emitErr != nil {
+			return emitErr
+		}
+		if !isConnLost(err) || reconnects >= max(c.retry.MaxAttempts, 1) || ctx.Err() != nil {
+			return fail(err)
+		}
+		if emitted {
+			if discard == nil {
+				return fail(err)
 			}
+			discard()
 		}
+		c.logger.Warn("connection lost, reconnecting", "chunk", i, "request_id", requestID, "attempt", reconnects+2, "err", err)
+		c.retried(ctx, RetryInfo{Attempt: reconnects + 2, Reconnect: true, Err: err})
+	}
+}
 
-	done:
-		if !audioReceived {
-			errCh <- ErrNoAudioReceived
+// stream 合成所有文本块，按顺序通过 emit 输出，emit 返回错误时停止
+func (c *Communicate) stream(ctx context.Context, emit func(TTSChunk) error) error {
+	if c.concurrency > 1 && len(c.texts) > 1 {
+		return c.streamParallel(ctx, emit)
+	}
+
+	// 顺序模式下所有文本块在同一个连接上依次合成
+	var conn *ttsConn
+	defer func() {
+		if conn != nil {
+			conn.Close()
 		}
 	}()
 
-	return chunkCh, errCh
+	for i, text := range c.texts {
+		c.state.PartialText = text
+		err := c.synthTurn(ctx, &conn, i, func(chunk TTSChunk) error {
+			return emit(c.compensate(i, chunk))
+		}, nil)
+		if err != nil {
+			return err
+		}
+		c.endTurn()
+	}
+	return nil
 }
 
 // Stream 流式获取音频和元数据
+// 合成在后台进行，所有发送都会响应 ctx 取消；消费方不再读取时应取消 ctx 或调用 Close，
+// 否则后台合成会一直等待。出错时错误先于 chunk channel 关闭发送到 error channel
 func (c *Communicate) Stream(ctx context.Context) (<-chan TTSChunk, <-chan error) {
 	chunkCh := make(chan TTSChunk, 100)
 	errCh := make(chan error, 1)
 
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	err := c.start(cancel, done)
+
 	go func() {
+		defer close(done)
+		defer cancel()
 		defer close(chunkCh)
 		defer close(errCh)
 
-		if c.state.StreamWasCalled {
-			errCh <- ErrStreamAlreadyCalled
+		if err != nil {
+			errCh <- err
 			return
 		}
-		c.state.StreamWasCalled = true
-
-		for _, text := range c.texts {
-			c.state.PartialText = text
-
-			innerChunkCh, innerErrCh := c.stream(ctx)
-
-			// 转发所有 chunks
-		loop:
-			for {
-				select {
-				case chunk, ok := <-innerChunkCh:
-					if !ok {
-						break loop
-					}
-					chunkCh <- chunk
-				case err, ok := <-innerErrCh:
-					if ok && err != nil {
-						errCh <- err
-						return
-					}
-				case <-ctx.Done():
-					errCh <- ctx.Err()
-					return
-				}
-			}
-
-			// 检查错误
+		ctx, span := c.startStreamSpan(ctx)
+		audioBytes := 0
+		err := c.stream(ctx, func(chunk TTSChunk) error {
 			select {
-			case err := <-innerErrCh:
-				if err != nil {
-					errCh <- err
-					return
-				}
-			default:
+			case chunkCh <- chunk:
+				audioBytes += len(chunk.Data)
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
 			}
+		})
+		endSpan(span, err, attrAudioBytes.Int(audioBytes))
+		if err != nil {
+			c.hooks.OnError(err)
+			errCh <- err
 		}
 	}()
 
 	return chunkCh, errCh
 }
 
+// start 标记合成开始并记录取消函数，供 Close 使用
+func (c *Communicate) start(cancel context.CancelFunc, done chan struct{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.closed:
+		return ErrClosed
+	case c.state.StreamWasCalled:
+		return ErrStreamAlreadyCalled
+	}
+	c.state.StreamWasCalled = true
+	c.cancel, c.done = cancel, done
+	return nil
+}
+
+// Close 取消正在进行的合成并等待后台 goroutine 退出、连接关闭，之后不能再合成
+// 可以重复调用，也可以与 Stream 的消费方并发调用
+func (c *Communicate) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	cancel, done := c.cancel, c.done
+	c.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return nil
+}
+
 // Save 保存音频和元数据到文件
 func (c *Communicate) Save(ctx context.Context, audioFname string, metadataFname string) error {
 	audioFile, err := os.Create(audioFname)
@@ -399,54 +488,28 @@ func (c *Communicate) Save(ctx context.Context, audioFname string, metadataFname
 		defer metadataFile.Close()
 	}
 
-	chunkCh, errCh := c.Stream(ctx)
-
-	for {
-		select {
-		case chunk, ok := <-chunkCh:
-			if !ok {
-				return nil
-			}
-			if chunk.Type == "audio" {
-				if _, err := audioFile.Write(chunk.Data); err != nil {
-					return err
-				}
-			} else if metadataFile != nil && (chunk.Type == "WordBoundary" || chunk.Type == "SentenceBoundary") {
-				data, _ := json.Marshal(chunk)
-				metadataFile.Write(data)
-				metadataFile.WriteString("\n")
-			}
-		case err := <-errCh:
-			if err != nil {
-				return err
-			}
-		case <-ctx.Done():
-			return ctx.Err()
+	return c.consume(ctx, func(chunk TTSChunk) error {
+		if chunk.Type == "audio" {
+			_, err := audioFile.Write(chunk.Data)
+			return err
 		}
-	}
+		if metadataFile != nil && (chunk.Type == "WordBoundary" || chunk.Type == "SentenceBoundary") {
+			data, _ := json.Marshal(chunk)
+			metadataFile.Write(data)
+			metadataFile.WriteString("\n")
+		}
+		return nil
+	})
 }
 
 // StreamSync 同步流式接口（使用 channel）
 func (c *Communicate) StreamSync(ctx context.Context) ([]TTSChunk, error) {
 	var chunks []TTSChunk
-
-	chunkCh, errCh := c.Stream(ctx)
-
-	for {
-		select {
-		case chunk, ok := <-chunkCh:
-			if !ok {
-				return chunks, nil
-			}
-			chunks = append(chunks, chunk)
-		case err := <-errCh:
-			if err != nil {
-				return chunks, err
-			}
-		case <-ctx.Done():
-			return chunks, ctx.Err()
-		}
-	}
+	err := c.consume(ctx, func(chunk TTSChunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	return chunks, err
 }
 
 // SaveSync 同步保存接口
@@ -456,24 +519,47 @@ func (c *Communicate) SaveSync(audioFname string, metadataFname string) error {
 
 // StreamToWriter 流式写入到 writer
 func (c *Communicate) StreamToWriter(ctx context.Context, w io.Writer, submaker *SubMaker) error {
+	return c.consume(ctx, func(chunk TTSChunk) error {
+		if chunk.Type == "audio" {
+			_, err := w.Write(chunk.Data)
+			return err
+		}
+		if submaker != nil && (chunk.Type == "WordBoundary" || chunk.Type == "SentenceBoundary") {
+			return submaker.Feed(chunk)
+		}
+		return nil
+	})
+}
+
+// consume 依次处理 Stream 输出的数据块，直到合成结束、出错、ctx 取消或 fn 返回错误
+// 返回前取消合成并等待后台 goroutine 退出，保证连接已经关闭
+func (c *Communicate) consume(ctx context.Context, fn func(TTSChunk) error) error {
+	ctx, cancel := context.WithCancel(ctx)
 	chunkCh, errCh := c.Stream(ctx)
+	defer func() {
+		cancel()
+		for range chunkCh {
+		}
+	}()
 
 	for {
 		select {
 		case chunk, ok := <-chunkCh:
 			if !ok {
-				return nil
-			}
-			if chunk.Type == "audio" {
-				if _, err := w.Write(chunk.Data); err != nil {
-					return err
-				}
-			} else if submaker != nil && (chunk.Type == "WordBoundary" || chunk.Type == "SentenceBoundary") {
-				if err := submaker.Feed(chunk); err != nil {
-					return err
+				// Stream 先关闭 errCh 再关闭 chunkCh，这里取出可能残留的错误
+				if errCh == nil {
+					return nil
 				}
+				return <-errCh
+			}
+			if err := fn(chunk); err != nil {
+				return err
+			}
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
 			}
-		case err := <-errCh:
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,463 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+// synthesize 对模拟服务执行一次合成，返回音频和边界
+func synthesize(t *testing.T, srv *edgettstest.Server, text string, opts ...edgetts.CommunicateOption) ([]byte, []edgetts.TTSChunk, error) {
+	t.Helper()
+	opts = append([]edgetts.CommunicateOption{edgetts.WithClient(srv.Client())}, opts...)
+	comm, err := edgetts.NewCommunicate(text, "", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	chunks, err := comm.StreamSync(ctx)
+
+	var audio []byte
+	var boundaries []edgetts.TTSChunk
+	for _, c := range chunks {
+		if c.Type == "audio" {
+			audio = append(audio, c.Data...)
+		} else {
+			boundaries = append(boundaries, c)
+		}
+	}
+	return audio, boundaries, err
+}
+
+func TestStreamWordBoundaries(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	audio, boundaries, err := synthesize(t, srv, "hello brave new world", edgetts.WithBoundary("WordBoundary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(audio) != "hellobravenewworld" {
+		t.Errorf("audio = %q", audio)
+	}
+
+	words := []string{"hello", "brave", "new", "world"}
+	if len(boundaries) != len(words) {
+		t.Fatalf("got %d boundaries, want %d", len(boundaries), len(words))
+	}
+	for i, b := range boundaries {
+		if b.Type != "WordBoundary" || b.Text != words[i] {
+			t.Errorf("boundary %d = %+v", i, b)
+		}
+		if want := float64(i * edgettstest.WordOffsetStep); b.Offset != want {
+			t.Errorf("boundary %d offset = %v, want %v", i, b.Offset, want)
+		}
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("server saw %d requests, want 1", len(reqs))
+	}
+	if !reqs[0].WordBoundary || reqs[0].OutputFormat != string(edgetts.DefaultOutputFormat) {
+		t.Errorf("request = %+v", reqs[0])
+	}
+	if !strings.Contains(reqs[0].SSML, "EmmaMultilingualNeural") {
+		t.Errorf("ssml = %q", reqs[0].SSML)
+	}
+}
+
+func TestStreamSentenceBoundary(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	_, boundaries, err := synthesize(t, srv, "one two three")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(boundaries) != 1 || boundaries[0].Type != "SentenceBoundary" || boundaries[0].Text != "one two three" {
+		t.Fatalf("boundaries = %+v", boundaries)
+	}
+}
+
+func TestStreamScriptedTurn(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithTurns(edgettstest.Turn{
+		Audio: [][]byte{[]byte("ID3"), []byte("frame")},
+		Boundaries: []edgettstest.Boundary{
+			{Type: "SentenceBoundary", Offset: 1000, Duration: 2000, Text: "a &amp; b"},
+		},
+	}))
+	defer srv.Close()
+
+	audio, boundaries, err := synthesize(t, srv, "anything")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(audio) != "ID3frame" {
+		t.Errorf("audio = %q", audio)
+	}
+	if len(boundaries) != 1 || boundaries[0].Text != "a & b" || boundaries[0].Offset != 1000 {
+		t.Errorf("boundaries = %+v", boundaries)
+	}
+}
+
+func TestStreamOffsetsAcrossChunks(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	text := strings.Repeat("word ", 1000)
+	_, boundaries, err := synthesize(t, srv, text, edgetts.WithBoundary("WordBoundary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs := srv.Requests()
+	if len(reqs) < 2 {
+		t.Fatalf("expected text to be split, got %d requests", len(reqs))
+	}
+	if len(boundaries) != 1000 {
+		t.Fatalf("got %d boundaries, want 1000", len(boundaries))
+	}
+
+	first := len(strings.Fields(reqs[0].Text))
+	last := boundaries[first-1]
+	want := last.Offset + last.Duration + 8_750_000
+	if got := boundaries[first].Offset; got != want {
+		t.Errorf("second chunk starts at %v, want %v", got, want)
+	}
+	for i := 1; i < len(boundaries); i++ {
+		if boundaries[i].Offset <= boundaries[i-1].Offset {
+			t.Fatalf("offsets not increasing at %d: %v <= %v", i, boundaries[i].Offset, boundaries[i-1].Offset)
+		}
+	}
+	if srv.Connections() != 1 {
+		t.Errorf("chunks used %d connections, want 1", srv.Connections())
+	}
+}
+
+func TestStreamReconnectsBetweenTurns(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithCloseAfterTurns(1))
+	defer srv.Close()
+
+	text := strings.Repeat("word ", 2000)
+	_, boundaries, err := synthesize(t, srv, text, edgetts.WithBoundary("WordBoundary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(boundaries) != 2000 {
+		t.Fatalf("got %d boundaries, want 2000", len(boundaries))
+	}
+	reqs := srv.Requests()
+	if srv.Connections() < 3 || srv.Connections() > len(reqs) {
+		t.Errorf("connections = %d for %d requests", srv.Connections(), len(reqs))
+	}
+	for i := 1; i < len(boundaries); i++ {
+		if boundaries[i].Offset <= boundaries[i-1].Offset {
+			t.Fatalf("offsets not increasing at %d", i)
+		}
+	}
+}
+
+func TestStreamOutputFormat(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	audio, _, err := synthesize(t, srv, "opus please", edgetts.WithOutputFormat(edgetts.Webm24khz16bitMonoOpus))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(audio) == 0 {
+		t.Fatal("no audio")
+	}
+	if got := srv.Requests()[0].OutputFormat; got != string(edgetts.Webm24khz16bitMonoOpus) {
+		t.Errorf("outputFormat = %q", got)
+	}
+}
+
+func TestStreamFaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault edgettstest.Fault
+		want  error
+	}{
+		{"unknown path", edgettstest.FaultUnknownPath, edgetts.ErrUnknownResponse},
+		{"binary path", edgettstest.FaultBinaryPath, edgetts.ErrUnexpectedResponse},
+		{"truncated header", edgettstest.FaultTruncatedHeader, edgetts.ErrUnexpectedResponse},
+		{"missing header length", edgettstest.FaultMissingHeaderLength, edgetts.ErrUnexpectedResponse},
+		{"content type", edgettstest.FaultContentType, edgetts.ErrUnexpectedResponse},
+		{"no audio", edgettstest.FaultNoAudio, edgetts.ErrNoAudioReceived},
+		{"close mid turn", edgettstest.FaultCloseMidTurn, nil},
+		{"bad metadata", edgettstest.FaultBadMetadata, nil},
+		{"forbidden", edgettstest.FaultForbidden, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := edgettstest.NewServer(edgettstest.WithFault(tt.fault))
+			defer srv.Close()
+
+			_, _, err := synthesize(t, srv, "hello world", edgetts.WithRetry(edgetts.NoRetry))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamRejectsStaleToken(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithClockSkew(time.Hour))
+	defer srv.Close()
+
+	if _, _, err := synthesize(t, srv, "hello", edgetts.WithRetry(edgetts.NoRetry)); err == nil {
+		t.Fatal("expected handshake to be rejected")
+	}
+	if srv.Rejected() == 0 {
+		t.Error("server did not reject the token")
+	}
+}
+
+func TestStreamReceiveTimeout(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFrameDelay(500 * time.Millisecond))
+	defer srv.Close()
+
+	start := time.Now()
+	_, _, err := synthesize(t, srv, "slow", edgetts.WithReceiveTimeout(50*time.Millisecond))
+	if err == nil {
+		t.Fatal("expected receive timeout")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("timeout took %v", time.Since(start))
+	}
+}
+
+func TestListVoicesFakeServer(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	voices, err := edgetts.ListVoices(context.Background(), &edgetts.ListVoicesOptions{Client: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(voices) != len(edgettstest.DefaultVoices()) {
+		t.Fatalf("got %d voices", len(voices))
+	}
+}
+
+func TestStreamConcurrentKeepsOrder(t *testing.T) {
+	// 第一个文本块最慢，后面的文本块会先完成
+	srv := edgettstest.NewServer(edgettstest.WithResponder(func(req *edgettstest.Request) edgettstest.Turn {
+		if strings.HasPrefix(req.Text, "w0000") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return edgettstest.Echo(req)
+	}))
+	defer srv.Close()
+
+	var words []string
+	for i := 0; i < 3000; i++ {
+		words = append(words, fmt.Sprintf("w%04d", i))
+	}
+
+	audio, boundaries, err := synthesize(t, srv, strings.Join(words, " "),
+		edgetts.WithBoundary("WordBoundary"), edgetts.WithConcurrency(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(audio) != strings.Join(words, "") {
+		t.Fatal("audio out of order")
+	}
+	if len(boundaries) != len(words) {
+		t.Fatalf("got %d boundaries, want %d", len(boundaries), len(words))
+	}
+	for i, b := range boundaries {
+		if b.Text != words[i] {
+			t.Fatalf("boundary %d = %q, want %q", i, b.Text, words[i])
+		}
+		if i > 0 && b.Offset <= boundaries[i-1].Offset {
+			t.Fatalf("offsets not increasing at %d", i)
+		}
+	}
+	if n := srv.Connections(); n < 2 || n > 3 {
+		t.Errorf("connections = %d, want 2..3", n)
+	}
+}
+
+func TestStreamConcurrentError(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFault(edgettstest.FaultNoAudio))
+	defer srv.Close()
+
+	_, _, err := synthesize(t, srv, strings.Repeat("word ", 3000), edgetts.WithConcurrency(4))
+	if !errors.Is(err, edgetts.ErrNoAudioReceived) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestStreamSSML(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	ssml := "<voice name='en-GB-SoniaNeural'>good <emphasis level='strong'>morning</emphasis><break time='300ms'/></voice>"
+	comm, err := edgetts.NewCommunicateSSML(ssml, "", edgetts.WithClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := comm.StreamSync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("server saw %d requests", len(reqs))
+	}
+	if !strings.Contains(reqs[0].SSML, ssml) || strings.Contains(reqs[0].SSML, "EmmaMultilingualNeural") {
+		t.Errorf("ssml = %s", reqs[0].SSML)
+	}
+}
+
+func TestStreamStyle(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	_, _, err := synthesize(t, srv, "great news",
+		edgetts.WithStyle("cheerful"), edgetts.WithStyleDegree(1.5), edgetts.WithRole("YoungAdultFemale"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssml := srv.Requests()[0].SSML
+	for _, want := range []string{
+		"xmlns:mstts='https://www.w3.org/2001/mstts'",
+		"<mstts:express-as style='cheerful' styledegree='1.5' role='YoungAdultFemale'><prosody",
+	} {
+		if !strings.Contains(ssml, want) {
+			t.Errorf("missing %q in %s", want, ssml)
+		}
+	}
+}
+
+func TestStreamStyleDropped(t *testing.T) {
+	// 模拟不支持说话风格的服务：带 express-as 的请求不返回音频
+	srv := edgettstest.NewServer(edgettstest.WithResponder(func(req *edgettstest.Request) edgettstest.Turn {
+		if strings.Contains(req.SSML, "express-as") {
+			return edgettstest.Turn{}
+		}
+		return edgettstest.Echo(req)
+	}))
+	defer srv.Close()
+
+	comm, err := edgetts.NewCommunicate("plain please", "", edgetts.WithClient(srv.Client()), edgetts.WithStyle("sad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := comm.StreamSync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) == 0 {
+		t.Fatal("no chunks")
+	}
+	warnings := comm.Warnings()
+	if len(warnings) != 1 || !errors.Is(warnings[0], edgetts.ErrStyleDropped) {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
+
+func TestStreamStyleDroppedRendered(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithResponder(func(req *edgettstest.Request) edgettstest.Turn {
+		if strings.Contains(req.SSML, "express-as") {
+			return edgettstest.Turn{}
+		}
+		return edgettstest.Echo(req)
+	}))
+	defer srv.Close()
+
+	opts := []edgetts.CommunicateOption{edgetts.WithClient(srv.Client()), edgetts.WithStyle("sad")}
+	newComms := map[string]func() (*edgetts.Communicate, error){
+		"markdown": func() (*edgetts.Communicate, error) {
+			return edgetts.NewCommunicate("# Title\n\nSome *plain* text.", "", append(opts, edgetts.WithInputFormat(edgetts.InputMarkdown))...)
+		},
+		"html": func() (*edgetts.Communicate, error) {
+			return edgetts.NewCommunicate("<p>Some <b>plain</b> text.</p>", "", append(opts, edgetts.WithInputFormat(edgetts.InputHTML))...)
+		},
+		"dialogue": func() (*edgetts.Communicate, error) {
+			return edgetts.NewCommunicateDialogue(edgetts.Dialogue{Turns: []edgetts.DialogueTurn{
+				{Speaker: "A", Text: "Hello."}, {Speaker: "B", Text: "Hi."},
+			}}, opts...)
+		},
+	}
+	for name, newComm := range newComms {
+		comm, err := newComm()
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks, err := comm.StreamSync(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(chunks) == 0 {
+			t.Errorf("%s: no chunks", name)
+		}
+		if warnings := comm.Warnings(); len(warnings) != 1 || !errors.Is(warnings[0], edgetts.ErrStyleDropped) {
+			t.Errorf("%s: warnings = %v", name, warnings)
+		}
+	}
+}
+
+func TestStyleValidation(t *testing.T) {
+	tests := []struct {
+		opts []edgetts.CommunicateOption
+		want error
+	}{
+		{[]edgetts.CommunicateOption{edgetts.WithStyle("grumpy")}, edgetts.ErrInvalidStyle},
+		{[]edgetts.CommunicateOption{edgetts.WithStyle("sad"), edgetts.WithStyleDegree(3)}, edgetts.ErrInvalidStyle},
+		{[]edgetts.CommunicateOption{edgetts.WithStyleDegree(1)}, edgetts.ErrInvalidStyle},
+		{[]edgetts.CommunicateOption{edgetts.WithRole("Wizard")}, edgetts.ErrInvalidRole},
+	}
+	for _, tt := range tests {
+		if _, err := edgetts.NewCommunicate("x", "", tt.opts...); !errors.Is(err, tt.want) {
+			t.Errorf("err = %v, want %v", err, tt.want)
+		}
+	}
+}
+
+func TestStyleValidationVoiceMetadata(t *testing.T) {
+	voices := edgetts.WithVoiceMetadata(
+		edgetts.Voice{ShortName: "zh-CN-XiaoxiaoNeural", StyleList: []string{"cheerful", "sad"}, RolePlayList: []string{"Girl"}},
+		edgetts.Voice{Name: "Microsoft Server Speech Text to Speech Voice (en-US, GuyNeural)", ShortName: "en-US-GuyNeural", StyleList: []string{"newscast"}},
+	)
+	tests := []struct {
+		voice string
+		opts  []edgetts.CommunicateOption
+		want  error
+	}{
+		{"zh-CN-XiaoxiaoNeural", []edgetts.CommunicateOption{edgetts.WithStyle("sad"), edgetts.WithRole("Girl")}, nil},
+		{"zh-CN-XiaoxiaoNeural", []edgetts.CommunicateOption{edgetts.WithStyle("newscast")}, edgetts.ErrInvalidStyle},
+		{"zh-CN-XiaoxiaoNeural", []edgetts.CommunicateOption{edgetts.WithRole("SeniorMale")}, edgetts.ErrInvalidRole},
+		{"en-US-GuyNeural", []edgetts.CommunicateOption{edgetts.WithStyle("cheerful")}, edgetts.ErrInvalidStyle},
+		// 没有 RolePlayList 的语音和不在列表中的语音只按已知值校验
+		{"en-US-GuyNeural", []edgetts.CommunicateOption{edgetts.WithStyle("newscast"), edgetts.WithRole("Boy")}, nil},
+		{"en-GB-SoniaNeural", []edgetts.CommunicateOption{edgetts.WithStyle("cheerful")}, nil},
+	}
+	for _, tt := range tests {
+		if _, err := edgetts.NewCommunicate("x", tt.voice, append(tt.opts, voices)...); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.voice, err, tt.want)
+		}
+	}
+
+	_, err := edgetts.NewCommunicateDialogue(edgetts.Dialogue{Turns: []edgetts.DialogueTurn{
+		{Speaker: "A", Voice: "zh-CN-XiaoxiaoNeural", Text: "你好"},
+		{Speaker: "B", Voice: "en-US-GuyNeural", Text: "Hi"},
+	}}, edgetts.WithStyle("sad"), voices)
+	if !errors.Is(err, edgetts.ErrInvalidStyle) {
+		t.Errorf("dialogue: err = %v, want ErrInvalidStyle", err)
+	}
+}
@@ -0,0 +1,347 @@
+package edgetts
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// turnPadding 相邻回合之间补偿的偏移量（100 纳秒单位）
+const turnPadding = 8_750_000
+
+// DefaultKeepAlive 默认的 WebSocket ping 间隔
+const DefaultKeepAlive = 20 * time.Second
+
+// WithKeepAlive 设置 WebSocket ping 间隔，0 表示不发送 ping
+func WithKeepAlive(interval time.Duration) CommunicateOption {
+	return func(c *Communicate) {
+		c.keepAlive = interval
+	}
+}
+
+// connLostError 连接在回合进行中断开，可以换新连接重试
+type connLostError struct {
+	err error
+}
+
+func (e *connLostError) Error() string {
+	return e.err.Error()
+}
+
+func (e *connLostError) Unwrap() error {
+	return e.err
+}
+
+// Is 连接断开匹配 ErrWebSocket
+func (e *connLostError) Is(target error) bool {
+	return target == ErrWebSocket
+}
+
+// isConnLost 判断错误是否由连接断开引起
+func isConnLost(err error) bool {
+	var cl *connLostError
+	return errors.As(err, &cl)
+}
+
+// ttsConn 一个 WebSocket 连接，可以在上面连续执行多个合成回合
+type ttsConn struct {
+	c    *Communicate
+	ws   *websocket.Conn
+	log  *slog.Logger // 带 connection_id 的日志记录器
+	stop chan struct{}
+	once sync.Once
+}
+
+// dial 建立 WebSocket 连接，握手失败时按重试策略重试，返回连接和它的 ConnectionId
+func (c *Communicate) dial(ctx context.Context) (*websocket.Conn, string, error) {
+	dialer, err := c.client.webSocketDialer(c.proxy, c.connectTimeout)
+	if err != nil {
+		return nil, "", err
+	}
+
+	drm := c.client.drm()
+	var conn *websocket.Conn
+	var connectionID string
+	attempt := 0
+	onRetry := func(n int, err error, wait time.Duration) {
+		c.logger.Warn("retrying websocket handshake", "attempt", n+1, "wait", wait, "err", err)
+		c.retried(ctx, RetryInfo{Attempt: n + 1, Err: err, Wait: wait})
+	}
+	err = c.retry.do(ctx, drm, onRetry, func() error {
+		// 每次尝试都重新生成 token，使时钟偏移校正生效
+		connectionID = ConnectID()
+		wsURL, err := c.client.webSocketURL(connectionID)
+		if err != nil {
+			return err
+		}
+
+		attempt++
+		info := DialInfo{Attempt: attempt, Start: time.Now()}
+		c.logger.Debug("dialing websocket", "url", redactURL(wsURL), "attempt", attempt)
+		c.hooks.OnDialStart(info)
+		dialCtx, span := c.tracer.Start(ctx, "edgetts.dial", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrAttempt.Int(attempt), attrConnectionID.String(connectionID)))
+		cn, resp, err := dialer.DialContext(dialCtx, wsURL, c.client.webSocketHeaders())
+		info.Duration = time.Since(info.Start)
+		if resp != nil {
+			info.StatusCode = resp.StatusCode
+			span.SetAttributes(attrStatusCode.Int(resp.StatusCode))
+		}
+		info.Err = err
+		c.hooks.OnDialDone(info)
+		if err != nil {
+			if resp != nil {
+				err = newHandshakeError(opWebSocketHandshake, resp, err)
+			} else {
+				err = fmt.Errorf("websocket dial error: %w", err)
+			}
+			endSpan(span, err)
+			return err
+		}
+		span.End()
+		c.logger.Debug("websocket connected", "connection_id", connectionID, "status", info.StatusCode, "duration", info.Duration)
+		conn = cn
+		return nil
+	})
+	return conn, connectionID, err
+}
+
+// openConn 建立连接并发送 speech.config
+func (c *Communicate) openConn(ctx context.Context) (*ttsConn, error) {
+	ws, connectionID, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &ttsConn{c: c, ws: ws, log: c.logger.With("connection_id", connectionID), stop: make(chan struct{})}
+	if err := tc.sendConfig(); err != nil {
+		tc.Close()
+		return nil, err
+	}
+	if c.keepAlive > 0 {
+		go tc.keepAlive(c.keepAlive)
+	}
+	return tc, nil
+}
+
+// Close 关闭连接
+func (tc *ttsConn) Close() {
+	tc.once.Do(func() {
+		close(tc.stop)
+		tc.ws.Close()
+	})
+}
+
+// keepAlive 定期发送 ping，防止连接在回合之间被中间设备断开
+func (tc *ttsConn) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-tc.stop:
+			return
+		case <-ticker.C:
+			// WriteControl 可以与其他写操作并发调用
+			if err := tc.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(tc.c.connectTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sendConfig 发送 speech.config，每个连接只需发送一次
+func (tc *ttsConn) sendConfig() error {
+	wd := "false"
+	sq := "true"
+	if tc.c.ttsConfig.Boundary == "WordBoundary" {
+		wd = "true"
+		sq = "false"
+	}
+
+	configMsg := fmt.Sprintf("X-Timestamp:%s\r\n"+
+		"Content-Type:application/json; charset=utf-8\r\n"+
+		"Path:speech.config\r\n\r\n"+
+		`{"context":{"synthesis":{"audio":{"metadataoptions":`+
+		`{"sentenceBoundaryEnabled":"%s","wordBoundaryEnabled":"%s"},`+
+		`"outputFormat":"%s"}}}}`+"\r\n",
+		DateToString(), sq, wd, tc.c.ttsConfig.Format)
+
+	if err := tc.ws.WriteMessage(websocket.TextMessage, []byte(configMsg)); err != nil {
+		return &connLostError{fmt.Errorf("write config error: %w", err)}
+	}
+	tc.log.Debug("sent speech.config", "bytes", len(configMsg))
+	tc.c.hooks.OnConfigSent(configMsg)
+	return nil
+}
+
+// turn 执行一个合成回合：以 info.RequestID 发送 info.SSML，读取到 turn.end 为止
+// emit 收到的边界偏移为本回合内的原始值，由调用方负责补偿；emit 返回错误时回合中止
+// ctx 取消时关闭连接，使阻塞的读取立即返回
+func (tc *ttsConn) turn(ctx context.Context, info TurnInfo, emit func(TTSChunk) error) error {
+	stop := context.AfterFunc(ctx, tc.Close)
+	defer stop()
+
+	hooks := &tc.c.hooks
+	span := trace.SpanFromContext(ctx)
+	info.Start = time.Now()
+	ssmlMsg := SSMLHeadersPlusData(info.RequestID, DateToString(), info.SSML)
+	if err := tc.ws.WriteMessage(websocket.TextMessage, []byte(ssmlMsg)); err != nil {
+		return &connLostError{fmt.Errorf("write ssml error: %w", err)}
+	}
+	tc.log.Debug("sent ssml", "request_id", info.RequestID, "chunk", info.Chunk, "bytes", len(ssmlMsg))
+	span.AddEvent("ssml.sent", trace.WithAttributes(attrRequestID.String(info.RequestID), attrSSMLBytes.Int(len(ssmlMsg))))
+	hooks.OnSSMLSent(info.elapsed())
+
+	audioReceived := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		tc.ws.SetReadDeadline(time.Now().Add(tc.c.receiveTimeout))
+		msgType, data, err := tc.ws.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return &connLostError{fmt.Errorf("read message error: %w", err)}
+		}
+
+		switch msgType {
+		case websocket.TextMessage:
+			// 找到 header 和 data 的分隔点
+			headerEnd := bytes.Index(data, []byte("\r\n\r\n"))
+			if headerEnd < 0 {
+				tc.log.Warn("dropped text message without headers", "request_id", info.RequestID, "bytes", len(data))
+				continue
+			}
+
+			headers, body := GetHeadersAndData(data, headerEnd)
+			body = bytes.TrimPrefix(body, []byte("\r\n\r\n"))
+			path := headers["Path"]
+			tc.log.Debug("received text message", "request_id", headers["X-RequestId"], "path", path, "bytes", len(body))
+
+			switch path {
+			case "audio.metadata":
+				parsed, dropped, err := parseMetadata(body)
+				if err != nil {
+					return &ProtocolError{Path: path, Header: headers, Frame: data, Err: err}
+				}
+				if dropped > 0 {
+					tc.log.Warn("dropped extra metadata entries", "request_id", info.RequestID, "count", dropped)
+				}
+				hooks.OnMetadata(info.elapsed(), *parsed)
+				if err := emit(*parsed); err != nil {
+					return err
+				}
+
+			case "turn.end":
+				if !audioReceived {
+					return &ProtocolError{Path: path, Header: headers, Frame: data, Err: ErrNoAudioReceived}
+				}
+				span.AddEvent("turn.end")
+				hooks.OnTurnEnd(info.elapsed())
+				return nil
+
+			case "turn.start":
+				span.AddEvent("turn.start")
+				hooks.OnTurnStart(info.elapsed())
+
+			case "response":
+				// 忽略
+
+			default:
+				return &ProtocolError{Path: path, Header: headers, Frame: data, Err: fmt.Errorf("%w: unknown path: %s", ErrUnknownResponse, path)}
+			}
+
+		case websocket.BinaryMessage:
+			if len(data) < 2 {
+				return &ProtocolError{Frame: data, Err: fmt.Errorf("%w: binary message missing header length", ErrUnexpectedResponse)}
+			}
+
+			headerLength := int(binary.BigEndian.Uint16(data[:2]))
+			if headerLength+2 > len(data) {
+				return &ProtocolError{Frame: data, Err: fmt.Errorf("%w: header length > data length", ErrUnexpectedResponse)}
+			}
+
+			// 跳过前 2 字节（长度），解析 headers 和 body
+			headers, body := GetHeadersAndData(data[2:], headerLength)
+			tc.log.Debug("received binary message", "request_id", headers["X-RequestId"], "path", headers["Path"], "bytes", len(body))
+			protocolError := func(format string, args ...any) error {
+				return &ProtocolError{
+					Path:   headers["Path"],
+					Header: headers,
+					Frame:  data,
+					Err:    fmt.Errorf("%w: "+format, append([]any{ErrUnexpectedResponse}, args...)...),
+				}
+			}
+
+			if headers["Path"] != "audio" {
+				return protocolError("binary message path is not audio")
+			}
+
+			contentType := headers["Content-Type"]
+			if contentType != "" && !tc.c.ttsConfig.Format.acceptsContentType(contentType) {
+				return protocolError("unexpected content type: %s", contentType)
+			}
+
+			if contentType == "" {
+				if len(body) == 0 {
+					continue
+				}
+				return protocolError("no content type but has data")
+			}
+
+			if len(body) == 0 {
+				return protocolError("audio content type but no data")
+			}
+
+			if !audioReceived {
+				span.AddEvent("first_audio")
+				audioReceived = true
+			}
+			hooks.OnAudio(info.elapsed(), body)
+			if err := emit(TTSChunk{Type: "audio", Data: body}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseMetadata 解析元数据，返回的偏移为回合内的原始值
+// 只使用第一个边界，dropped 为被忽略的其余条目数
+func parseMetadata(data []byte) (chunk *TTSChunk, dropped int, err error) {
+	var resp MetadataResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, 0, fmt.Errorf("%w: invalid metadata: %v", ErrUnexpectedResponse, err)
+	}
+
+	for i, meta := range resp.Metadata {
+		if meta.Type == "WordBoundary" || meta.Type == "SentenceBoundary" {
+			return &TTSChunk{
+				Type:     meta.Type,
+				Offset:   meta.Data.Offset,
+				Duration: meta.Data.Duration,
+				Text:     UnescapeXML(meta.Data.Text.Text),
+			}, len(resp.Metadata) - i - 1, nil
+		}
+		if meta.Type == "SessionEnd" {
+			continue
+		}
+		return nil, 0, fmt.Errorf("%w: unknown metadata type: %s", ErrUnknownResponse, meta.Type)
+	}
+
+	return nil, 0, fmt.Errorf("%w: no WordBoundary metadata found", ErrUnexpectedResponse)
+}
@@ -1,7 +1,5 @@
 package edgetts
 
-import "fmt"
-
 const (
 	// 基础 URL
 	BaseURL            = "speech.platform.bing.com/consumer/speech/synthesize/readaloud"
@@ -19,6 +17,9 @@ const (
 	ChromiumMajorVersion = "143"
 	SecMSGECVersion      = "1-" + ChromiumFullVersion
 
+	// Edge 朗读扩展的 Origin
+	EdgeOrigin = "chrome-extension://jdiccldimpdaibmpdkjnbmckianbfold"
+
 	// Windows 纪元偏移量（秒）
 	WinEpoch = 11644473600
 	// 秒到纳秒
@@ -28,7 +29,7 @@ const (
 var (
 	// 基础 HTTP headers
 	BaseHeaders = map[string]string{
-		"User-Agent":      fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s.0.0.0 Safari/537.36 Edg/%s.0.0.0", ChromiumMajorVersion, ChromiumMajorVersion),
+		"User-Agent":      userAgent(ChromiumMajorVersion),
 		"Accept-Encoding": "gzip, deflate, br, zstd",
 		"Accept-Language": "en-US,en;q=0.9",
 	}
@@ -37,13 +38,13 @@ var (
 	WSSHeaders = map[string]string{
 		"Pragma":       "no-cache",
 		"Cache-Control": "no-cache",
-		"Origin":       "chrome-extension://jdiccldimpdaibmpdkjnbmckianbfold",
+		"Origin":       EdgeOrigin,
 	}
 
 	// 语音列表请求 headers
 	VoiceHeaders = map[string]string{
 		"Authority":       "speech.platform.bing.com",
-		"Sec-CH-UA":       fmt.Sprintf(`" Not;A Brand";v="99", "Microsoft Edge";v="%s", "Chromium";v="%s"`, ChromiumMajorVersion, ChromiumMajorVersion),
+		"Sec-CH-UA":       secCHUA(ChromiumMajorVersion),
 		"Sec-CH-UA-Mobile": "?0",
 		"Accept":          "*/*",
 		"Sec-Fetch-Site":  "none",
@@ -0,0 +1,94 @@
+package edgetts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// DialogueTurn 对话中的一句
+// Voice、Rate、Volume、Pitch 为空时使用 NewCommunicateDialogue 选项中的设置
+type DialogueTurn struct {
+	Speaker string `json:"speaker"`
+	Voice   string `json:"voice,omitempty"`
+	Rate    string `json:"rate,omitempty"`
+	Volume  string `json:"volume,omitempty"`
+	Pitch   string `json:"pitch,omitempty"`
+	Text    string `json:"text"`
+}
+
+// Dialogue 多语音对话
+type Dialogue struct {
+	Turns []DialogueTurn `json:"turns"`
+	// Gap 相邻两句之间的停顿，通过 <break> 插入到后一句开头
+	Gap time.Duration `json:"gap,omitempty"`
+}
+
+// maxDialogueGap 服务端支持的最长停顿
+const maxDialogueGap = 5 * time.Second
+
+// NewCommunicateDialogue 创建对话合成实例
+// 所有句子在同一个音频流中按顺序合成，边界偏移覆盖整个对话，并带有说话人
+func NewCommunicateDialogue(d Dialogue, opts ...CommunicateOption) (*Communicate, error) {
+	if len(d.Turns) == 0 {
+		return nil, errors.New("dialogue has no turns")
+	}
+	if d.Gap < 0 || d.Gap > maxDialogueGap {
+		return nil, fmt.Errorf("dialogue gap %v out of range 0-%v", d.Gap, maxDialogueGap)
+	}
+
+	c, err := newCommunicate("", opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, turn := range d.Turns {
+		tc := *c.ttsConfig
+		if turn.Voice != "" {
+			tc.Voice = turn.Voice
+		}
+		if turn.Rate != "" {
+			tc.Rate = turn.Rate
+		}
+		if turn.Volume != "" {
+			tc.Volume = turn.Volume
+		}
+		if turn.Pitch != "" {
+			tc.Pitch = turn.Pitch
+		}
+		if err := ValidateTTSConfig(&tc); err != nil {
+			return nil, fmt.Errorf("dialogue turn %d (%s): %w", i, turn.Speaker, err)
+		}
+		if err := validateVoiceStyle(&tc, c.voiceMetadata); err != nil {
+			return nil, fmt.Errorf("dialogue turn %d (%s): %w", i, turn.Speaker, err)
+		}
+
+		// 停顿插入到第一块开头，切分时预留它的长度
+		var gap []byte
+		chunkSize := c.chunkSize
+		if i > 0 && d.Gap > 0 {
+			gap = []byte(fmt.Sprintf("<break time='%dms'/>", d.Gap.Milliseconds()))
+			chunkSize -= len(gap)
+			if chunkSize <= 0 {
+				return nil, fmt.Errorf("%w: %d is too small for the dialogue gap", ErrInvalidChunkSize, c.chunkSize)
+			}
+		}
+
+		escaped := EscapeXML(RemoveIncompatibleCharacters(turn.Text))
+		chunks := c.splitter.Split(escaped, chunkSize)
+		if len(chunks) == 0 {
+			return nil, fmt.Errorf("dialogue turn %d (%s): empty text", i, turn.Speaker)
+		}
+		c.characters += utf8.RuneCountInString(turn.Text)
+		for j, text := range chunks {
+			if j == 0 && gap != nil {
+				text = append(gap, text...)
+			}
+			c.texts = append(c.texts, []byte(MKSSML(&tc, string(text))))
+			c.speakers = append(c.speakers, turn.Speaker)
+		}
+	}
+	c.rawSSML = true
+	return c, nil
+}
@@ -0,0 +1,142 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+func TestDialogue(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	d := edgetts.Dialogue{
+		Gap: 300 * time.Millisecond,
+		Turns: []edgetts.DialogueTurn{
+			{Speaker: "Alice", Voice: "en-US-EmmaMultilingualNeural", Text: "hi bob"},
+			{Speaker: "Bob", Voice: "en-GB-SoniaNeural", Rate: "+10%", Text: "hello alice"},
+			{Speaker: "Alice", Text: "bye"},
+		},
+	}
+	comm, err := edgetts.NewCommunicateDialogue(d, edgetts.WithClient(srv.Client()), edgetts.WithVoice("en-US-GuyNeural"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := comm.StreamSync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sm := edgetts.NewSubMaker()
+	var audio []byte
+	for _, c := range chunks {
+		if c.Type == "audio" {
+			audio = append(audio, c.Data...)
+		} else if err := sm.Feed(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(audio) != "hibobhelloalicebye" {
+		t.Errorf("audio = %q", audio)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("server saw %d requests", len(reqs))
+	}
+	for i, want := range []string{"EmmaMultilingualNeural", "SoniaNeural", "GuyNeural"} {
+		if !strings.Contains(reqs[i].SSML, want) {
+			t.Errorf("request %d ssml = %s", i, reqs[i].SSML)
+		}
+	}
+	if strings.Contains(reqs[0].SSML, "<break") || !strings.Contains(reqs[1].SSML, "<break time='300ms'/>") {
+		t.Errorf("gap not applied: %s / %s", reqs[0].SSML, reqs[1].SSML)
+	}
+	if !strings.Contains(reqs[1].SSML, "rate='+10%'") {
+		t.Errorf("prosody not applied: %s", reqs[1].SSML)
+	}
+
+	if len(sm.Cues) != 3 {
+		t.Fatalf("cues = %+v", sm.Cues)
+	}
+	for i, speaker := range []string{"Alice", "Bob", "Alice"} {
+		if sm.Cues[i].Speaker != speaker {
+			t.Errorf("cue %d speaker = %q", i, sm.Cues[i].Speaker)
+		}
+		if i > 0 && sm.Cues[i].Start <= sm.Cues[i-1].End {
+			t.Errorf("cue %d starts at %v before previous end %v", i, sm.Cues[i].Start, sm.Cues[i-1].End)
+		}
+	}
+
+	srt := sm.GetSRT()
+	if !strings.Contains(srt, "Alice: hi bob") || !strings.Contains(srt, "Bob: hello alice") {
+		t.Errorf("srt = %s", srt)
+	}
+	vtt := sm.GetVTT()
+	if !strings.HasPrefix(vtt, "WEBVTT\n\n") || !strings.Contains(vtt, "<v Bob>hello alice") {
+		t.Errorf("vtt = %s", vtt)
+	}
+}
+
+func TestDialogueInvalidTurn(t *testing.T) {
+	_, err := edgetts.NewCommunicateDialogue(edgetts.Dialogue{
+		Turns: []edgetts.DialogueTurn{{Speaker: "Alice", Rate: "fast", Text: "hi"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Alice") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestDialogueGapFitsChunkSize(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	const chunkSize = 40
+	comm, err := edgetts.NewCommunicateDialogue(edgetts.Dialogue{
+		Gap: 500 * time.Millisecond,
+		Turns: []edgetts.DialogueTurn{
+			{Speaker: "Alice", Text: "one two three four five six seven eight"},
+			{Speaker: "Bob", Text: "nine ten eleven twelve thirteen fourteen"},
+		},
+	}, edgetts.WithClient(srv.Client()), edgetts.WithChunkSize(chunkSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comm.StreamSync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	content := regexp.MustCompile(`<prosody[^>]*>(.*)</prosody>`)
+	for _, req := range srv.Requests() {
+		m := content.FindStringSubmatch(req.SSML)
+		if m == nil {
+			t.Fatalf("ssml = %s", req.SSML)
+		}
+		if len(m[1]) > chunkSize {
+			t.Errorf("chunk of %d bytes exceeds %d: %s", len(m[1]), chunkSize, m[1])
+		}
+	}
+
+	_, err = edgetts.NewCommunicateDialogue(edgetts.Dialogue{
+		Gap:   time.Second,
+		Turns: []edgetts.DialogueTurn{{Speaker: "Alice", Text: "hi"}, {Speaker: "Bob", Text: "hello"}},
+	}, edgetts.WithChunkSize(10))
+	if !errors.Is(err, edgetts.ErrInvalidChunkSize) {
+		t.Errorf("err = %v, want ErrInvalidChunkSize", err)
+	}
+}
+
+func TestDialogueEmptyTurn(t *testing.T) {
+	_, err := edgetts.NewCommunicateDialogue(edgetts.Dialogue{
+		Turns: []edgetts.DialogueTurn{{Speaker: "Alice", Text: "hi"}, {Speaker: "Bob", Text: " \x00 "}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Bob") {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package edgetts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InputFormat NewCommunicate 输入文本的格式
+type InputFormat string
+
+const (
+	// InputText 纯文本，原样朗读
+	InputText InputFormat = "text"
+	// InputMarkdown Markdown 文档，朗读时去掉标记
+	InputMarkdown InputFormat = "markdown"
+	// InputHTML HTML 文档，朗读时去掉标签
+	InputHTML InputFormat = "html"
+)
+
+// ParseInputFormat 解析输入格式名称，md 和 htm 分别作为 markdown 和 html 的别名
+func ParseInputFormat(s string) (InputFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "text", "txt":
+		return InputText, nil
+	case "markdown", "md":
+		return InputMarkdown, nil
+	case "html", "htm":
+		return InputHTML, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidInputFormat, s)
+}
+
+// InputFormatForExtension 根据文件扩展名推断输入格式
+func InputFormatForExtension(ext string) (InputFormat, bool) {
+	switch strings.ToLower(ext) {
+	case ".md", ".markdown":
+		return InputMarkdown, true
+	case ".html", ".htm", ".xhtml":
+		return InputHTML, true
+	case ".txt":
+		return InputText, true
+	}
+	return "", false
+}
+
+// CodeBlockMode 代码块的朗读方式
+type CodeBlockMode int
+
+const (
+	// CodeSkip 跳过代码块，只保留停顿
+	CodeSkip CodeBlockMode = iota
+	// CodeAnnounce 用 DocumentOptions.CodeAnnouncement 代替代码块
+	CodeAnnounce
+	// CodeRead 朗读代码块内容
+	CodeRead
+)
+
+// ParseCodeBlockMode 解析代码块朗读方式：skip、announce 或 read
+func ParseCodeBlockMode(s string) (CodeBlockMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "skip":
+		return CodeSkip, nil
+	case "announce":
+		return CodeAnnounce, nil
+	case "read":
+		return CodeRead, nil
+	}
+	return 0, fmt.Errorf("invalid code block mode %q (want skip, announce or read)", s)
+}
+
+// DocumentOptions Markdown 和 HTML 输入的朗读方式
+// 停顿通过 <break> 插入，为 0 时不插入，最长 5 秒
+type DocumentOptions struct {
+	// HeadingPause 标题之后的停顿
+	HeadingPause time.Duration
+	// EmphasizeHeadings 用 <emphasis> 朗读标题
+	EmphasizeHeadings bool
+	// ParagraphPause 段落、引用、分隔线等块级元素之间的停顿
+	ParagraphPause time.Duration
+	// ItemPause 列表项、表格行之间的停顿
+	ItemPause time.Duration
+	// CodeBlocks 代码块的朗读方式
+	CodeBlocks CodeBlockMode
+	// CodeAnnouncement CodeAnnounce 模式下代替代码块朗读的文本
+	CodeAnnouncement string
+}
+
+// DefaultDocumentOptions 默认的文档朗读方式
+var DefaultDocumentOptions = DocumentOptions{
+	HeadingPause:     750 * time.Millisecond,
+	ParagraphPause:   500 * time.Millisecond,
+	ItemPause:        300 * time.Millisecond,
+	CodeBlocks:       CodeSkip,
+	CodeAnnouncement: "Code block.",
+}
+
+// WithInputFormat 设置 NewCommunicate 的输入格式，默认 InputText
+// Markdown 和 HTML 输入按文档结构朗读，边界的 SourceStart/SourceEnd 指向原始文档
+func WithInputFormat(f InputFormat) CommunicateOption {
+	return func(c *Communicate) {
+		c.inputFormat = f
+	}
+}
+
+// WithDocumentOptions 设置 Markdown 和 HTML 输入的朗读方式，默认 DefaultDocumentOptions
+func WithDocumentOptions(opts DocumentOptions) CommunicateOption {
+	return func(c *Communicate) {
+		c.documentOptions = opts
+	}
+}
+
+// validate 校验停顿和代码块设置
+func (o *DocumentOptions) validate() error {
+	for _, d := range []time.Duration{o.HeadingPause, o.ParagraphPause, o.ItemPause} {
+		if d < 0 || d > maxDialogueGap {
+			return fmt.Errorf("document pause %v out of range 0-%v", d, maxDialogueGap)
+		}
+	}
+	if o.CodeBlocks < CodeSkip || o.CodeBlocks > CodeRead {
+		return fmt.Errorf("invalid code block mode %d", o.CodeBlocks)
+	}
+	return nil
+}
+
+// docSegment 文档中要朗读的一段文本，对应源文档 [srcStart, srcEnd)
+type docSegment struct {
+	text             string
+	srcStart, srcEnd int
+	identity         bool // text 与源文档逐字节对应
+	emphasis         bool
+	pause            time.Duration // 之后的停顿
+}
+
+// docBuilder 供 Markdown 和 HTML 解析器收集朗读文本和停顿
+type docBuilder struct {
+	opts     DocumentOptions
+	segs     []docSegment
+	emphasis bool
+}
+
+// text 添加源文档中 start 处逐字节朗读的文本
+func (b *docBuilder) text(s string, start int) {
+	if s == "" {
+		return
+	}
+	if n := len(b.segs); n > 0 {
+		last := &b.segs[n-1]
+		if last.identity && last.srcEnd == start && last.emphasis == b.emphasis && last.pause == 0 {
+			last.text += s
+			last.srcEnd += len(s)
+			return
+		}
+	}
+	b.segs = append(b.segs, docSegment{text: s, srcStart: start, srcEnd: start + len(s), identity: true, emphasis: b.emphasis})
+}
+
+// spoken 添加代替源文档 [start, end) 朗读的文本
+func (b *docBuilder) spoken(s string, start, end int) {
+	if s == "" {
+		return
+	}
+	b.segs = append(b.segs, docSegment{text: s, srcStart: start, srcEnd: end, emphasis: b.emphasis})
+}
+
+// pause 在已添加的文本之后停顿，连续的停顿取最长的一个，文档开头的停顿被忽略
+func (b *docBuilder) pause(d time.Duration) {
+	if n := len(b.segs); n > 0 && d > 0 {
+		b.segs[n-1].pause = max(b.segs[n-1].pause, d)
+	}
+}
+
+// setDocument 把 Markdown 或 HTML 文档转换为 SSML 文本块
+func (c *Communicate) setDocument(source string) error {
+	var segs []docSegment
+	switch c.inputFormat {
+	case InputMarkdown:
+		segs = parseMarkdown(source, c.documentOptions)
+	case InputHTML:
+		segs = parseHTML(source, c.documentOptions)
+	}
+
+	content, tracker, err := renderDocument(source, segs, c.processors)
+	if err != nil {
+		return err
+	}
+	c.texts, err = SplitSSML(MKSSML(c.ttsConfig, content), c.chunkSize)
+	if err != nil {
+		return err
+	}
+	c.tracker = tracker
+	c.rawSSML = true
+	return nil
+}
+
+// renderDocument 对每段文本执行处理链，生成转义后的 SSML 内容和定位边界用的 sourceTracker
+func renderDocument(source string, segs []docSegment, procs []TextProcessor) (string, *sourceTracker, error) {
+	var content, plain strings.Builder
+	m := &documentMap{}
+	emphasis := false
+	setEmphasis := func(on bool) {
+		if on == emphasis {
+			return
+		}
+		if on {
+			content.WriteString("<emphasis level='moderate'>")
+		} else {
+			content.WriteString("</emphasis>")
+		}
+		emphasis = on
+	}
+
+	for _, seg := range segs {
+		processed, offsets, err := ProcessText(seg.text, procs...)
+		if err != nil {
+			return "", nil, err
+		}
+		setEmphasis(seg.emphasis)
+		content.WriteString(EscapeXML(processed))
+		if processed != "" {
+			m.pieces = append(m.pieces, docPiece{
+				outStart: plain.Len(),
+				outEnd:   plain.Len() + len(processed),
+				seg:      seg,
+				offsets:  offsets,
+			})
+			plain.WriteString(processed)
+		}
+		if seg.pause > 0 {
+			setEmphasis(false)
+			fmt.Fprintf(&content, "<break time='%dms'/>", seg.pause.Milliseconds())
+			// 停顿两侧是不同的词，避免定位边界时跨越停顿匹配
+			plain.WriteByte('\n')
+		}
+	}
+	setEmphasis(false)
+
+	return content.String(), &sourceTracker{source: source, processed: plain.String(), offsets: m}, nil
+}
+
+// docPiece 朗读文本 [outStart, outEnd) 由 seg 经处理链得到
+type docPiece struct {
+	outStart, outEnd int
+	seg              docSegment
+	offsets          *OffsetMap
+}
+
+// documentMap 朗读文本到源文档的位置映射
+type documentMap struct {
+	pieces []docPiece
+}
+
+// Source 实现 sourceMap
+// 范围的两端可能落在停顿插入的换行上，此时分别取之后和之前最近的一段
+func (m *documentMap) Source(start, end int) (int, int) {
+	n := len(m.pieces)
+	if n == 0 || end <= start {
+		return 0, 0
+	}
+
+	i := min(sort.Search(n, func(i int) bool { return m.pieces[i].outEnd > start }), n-1)
+	p := m.pieces[i]
+	srcStart := p.seg.srcStart
+	if p.seg.identity {
+		pos := max(start-p.outStart, 0)
+		local, _ := p.offsets.Source(pos, pos+1)
+		srcStart += local
+	}
+
+	j := max(sort.Search(n, func(i int) bool { return m.pieces[i].outStart >= end })-1, 0)
+	p = m.pieces[j]
+	srcEnd := p.seg.srcEnd
+	if p.seg.identity {
+		pos := min(end-p.outStart, p.outEnd-p.outStart)
+		_, local := p.offsets.Source(pos-1, pos)
+		srcEnd = p.seg.srcStart + local
+	}
+	return srcStart, max(srcStart, srcEnd)
+}
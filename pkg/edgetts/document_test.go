@@ -0,0 +1,190 @@
+package edgetts_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+func TestMarkdownInput(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	md := "# Getting *started*\n\n" +
+		"Read the [install guide](https://example.com/install) and run `make`.\n\n" +
+		"- first item\n" +
+		"- second &amp; last\n\n" +
+		"```sh\nrm -rf /\n```\n\n" +
+		"![A cat](cat.png) sat on snake_case.\n"
+	_, _, err := synthesize(t, srv, md,
+		edgetts.WithInputFormat(edgetts.InputMarkdown),
+		edgetts.WithDocumentOptions(edgetts.DocumentOptions{
+			HeadingPause:      700 * time.Millisecond,
+			EmphasizeHeadings: true,
+			ItemPause:         200 * time.Millisecond,
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := srv.Requests()[0]
+	want := "Getting started Read the install guide and run make.\n first item\n second & last\nA cat sat on snake_case.\n"
+	if req.Text != want {
+		t.Errorf("text = %q, want %q", req.Text, want)
+	}
+	for _, s := range []string{
+		"<emphasis level='moderate'>Getting started</emphasis><break time='700ms'/>",
+		"<break time='200ms'/>second &amp; last",
+	} {
+		if !strings.Contains(req.SSML, s) {
+			t.Errorf("ssml missing %q:\n%s", s, req.SSML)
+		}
+	}
+}
+
+func TestHTMLInput(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	page := `<!DOCTYPE html><html><head><title>Page</title><style>p { color: red }</style></head>
+<body><h2>News</h2><p>Tom &amp; Jerry <a href="https://example.com">visit</a>.</p>
+<script>alert("hi")</script><ul><li>One</li><li>Two</li></ul><pre>x := 1</pre></body></html>`
+	_, _, err := synthesize(t, srv, page,
+		edgetts.WithInputFormat(edgetts.InputHTML),
+		edgetts.WithDocumentOptions(edgetts.DocumentOptions{
+			HeadingPause:     time.Second,
+			ItemPause:        100 * time.Millisecond,
+			CodeBlocks:       edgetts.CodeAnnounce,
+			CodeAnnouncement: "Code omitted.",
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := srv.Requests()[0]
+	if got := strings.Join(strings.Fields(req.Text), " "); got != "News Tom & Jerry visit. One Two Code omitted." {
+		t.Errorf("text = %q", got)
+	}
+	for _, s := range []string{"News<break time='1000ms'/>", "One<break time='100ms'/>"} {
+		if !strings.Contains(req.SSML, s) {
+			t.Errorf("ssml missing %q:\n%s", s, req.SSML)
+		}
+	}
+	for _, s := range []string{"alert", "color", "Page", "https"} {
+		if strings.Contains(req.Text, s) {
+			t.Errorf("text contains %q: %q", s, req.Text)
+		}
+	}
+}
+
+func TestMarkdownRawHTMLScript(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	md := "Before <script>alert(1)</script> after <STYLE type=\"text/css\">b { x: y }</STYLE>.\n\n" +
+		"<script>\nvar secret = 1;\n</script>\n\n" +
+		"  <style>\np { color: red }\n</style>\n" +
+		"Done.\n"
+	_, _, err := synthesize(t, srv, md, edgetts.WithInputFormat(edgetts.InputMarkdown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(strings.Fields(srv.Requests()[0].Text), " "); got != "Before after . Done." {
+		t.Errorf("text = %q", got)
+	}
+}
+
+// 小写后字节长度变化的字符（Ⱥ 变长、İ 变短）不影响结束标签的位置
+func TestMarkdownRawHTMLCaseChangingText(t *testing.T) {
+	for _, md := range []string{
+		"Hello <script>" + strings.Repeat("Ⱥ", 20) + "</script> world.",
+		"Hello <style>" + strings.Repeat("İ", 20) + "</STYLE> world.",
+		"<script>\n" + strings.Repeat("Ⱥİ", 10) + "\n</Script>\n\nHello world.\n",
+	} {
+		srv := edgettstest.NewServer()
+		_, _, err := synthesize(t, srv, md, edgetts.WithInputFormat(edgetts.InputMarkdown))
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(strings.Fields(srv.Requests()[0].Text), " "); got != "Hello world." {
+			t.Errorf("%q: text = %q", md, got)
+		}
+	}
+}
+
+func TestCodeBlockModes(t *testing.T) {
+	md := "Intro.\n\n```\nprint(1)\n```\n"
+	tests := []struct {
+		mode edgetts.CodeBlockMode
+		want string
+	}{
+		{edgetts.CodeSkip, "Intro."},
+		{edgetts.CodeAnnounce, "Intro. Code block."},
+		{edgetts.CodeRead, "Intro. print(1)"},
+	}
+	for _, tt := range tests {
+		srv := edgettstest.NewServer()
+		opts := edgetts.DefaultDocumentOptions
+		opts.CodeBlocks = tt.mode
+		_, _, err := synthesize(t, srv, md, edgetts.WithInputFormat(edgetts.InputMarkdown), edgetts.WithDocumentOptions(opts))
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(strings.Fields(srv.Requests()[0].Text), " "); got != tt.want {
+			t.Errorf("mode %d: text = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestDocumentSourceText(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	md := "## Intro\n\nSee **the** [docs](http://x.y) for 3 tips."
+	_, boundaries, err := synthesize(t, srv, md,
+		edgetts.WithBoundary("WordBoundary"),
+		edgetts.WithInputFormat(edgetts.InputMarkdown),
+		edgetts.WithTextProcessors(edgetts.EnglishNumberProcessor))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"Intro": "Intro", "See": "See", "the": "the", "docs": "docs", "for": "for", "three": "3", "tips.": "tips."}
+	if len(boundaries) != len(want) {
+		t.Fatalf("boundaries = %+v", boundaries)
+	}
+	for _, b := range boundaries {
+		if b.SourceText != want[b.Text] {
+			t.Errorf("boundary %q source = %q, want %q", b.Text, b.SourceText, want[b.Text])
+		}
+		if md[b.SourceStart:b.SourceEnd] != b.SourceText {
+			t.Errorf("boundary %q range [%d, %d) does not match source text", b.Text, b.SourceStart, b.SourceEnd)
+		}
+	}
+}
+
+func TestInputFormatValidation(t *testing.T) {
+	if _, err := edgetts.NewCommunicate("x", "", edgetts.WithInputFormat("rtf")); !errors.Is(err, edgetts.ErrInvalidInputFormat) {
+		t.Errorf("err = %v, want ErrInvalidInputFormat", err)
+	}
+	opts := edgetts.DefaultDocumentOptions
+	opts.HeadingPause = time.Minute
+	if _, err := edgetts.NewCommunicate("x", "", edgetts.WithDocumentOptions(opts)); err == nil {
+		t.Error("expected error for long pause")
+	}
+
+	for s, want := range map[string]edgetts.InputFormat{"md": edgetts.InputMarkdown, "HTML": edgetts.InputHTML, "": edgetts.InputText} {
+		if got, err := edgetts.ParseInputFormat(s); err != nil || got != want {
+			t.Errorf("ParseInputFormat(%q) = %q, %v", s, got, err)
+		}
+	}
+	if f, ok := edgetts.InputFormatForExtension(".Markdown"); !ok || f != edgetts.InputMarkdown {
+		t.Errorf("InputFormatForExtension = %q, %v", f, ok)
+	}
+}
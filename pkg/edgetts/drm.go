@@ -12,13 +12,30 @@ import (
 )
 
 // DRM 处理 DRM 操作和时钟偏移校正
+// 每个实例有自己的客户端标识和时钟偏移，零值使用 DefaultClientIdentity
 type DRM struct {
 	mu               sync.RWMutex
 	clockSkewSeconds float64
+	identity         ClientIdentity
+	muid             string // MUIDPersistent 策略下生成的 MUID
 }
 
 var globalDRM = &DRM{}
 
+// NewDRM 创建使用 identity 的 DRM 实例，identity 中的空字段使用默认值
+// 通过 Client.DRM 指定给 Communicate、Synthesizer 和 ListVoices
+func NewDRM(identity ClientIdentity) (*DRM, error) {
+	if err := identity.Validate(); err != nil {
+		return nil, err
+	}
+	return &DRM{identity: identity.withDefaults()}, nil
+}
+
+// Identity 返回实例的客户端标识
+func (d *DRM) Identity() ClientIdentity {
+	return d.identity.withDefaults()
+}
+
 // AdjClockSkewSeconds 调整时钟偏移
 func (d *DRM) AdjClockSkewSeconds(skewSeconds float64) {
 	d.mu.Lock()
@@ -46,7 +63,8 @@ func ParseRFC2616Date(dateStr string) (float64, error) {
 	return float64(t.Unix()), nil
 }
 
-// HandleClientResponseError 处理客户端响应错误，调整时钟偏移
+// HandleClientResponseError 按 403 响应的 Date 头校正时钟偏移
+// 握手和语音列表的重试已经自动校正，只有自行发送请求时才需要调用
 func (d *DRM) HandleClientResponseError(resp *http.Response) error {
 	if resp == nil || resp.Header == nil {
 		return fmt.Errorf("%w: no server date in headers", ErrSkewAdjustment)
@@ -62,11 +80,15 @@ func (d *DRM) HandleClientResponseError(resp *http.Response) error {
 		return fmt.Errorf("%w: failed to parse server date: %s", ErrSkewAdjustment, serverDate)
 	}
 
-	clientTimestamp := d.GetUnixTimestamp()
-	d.AdjClockSkewSeconds(serverTimestamp - clientTimestamp)
+	d.syncServerTime(time.Unix(int64(serverTimestamp), 0))
 	return nil
 }
 
+// syncServerTime 按服务端时间校正时钟偏移，HandleClientResponseError 和重试共用
+func (d *DRM) syncServerTime(serverTime time.Time) {
+	d.AdjClockSkewSeconds(float64(serverTime.Unix()) - d.GetUnixTimestamp())
+}
+
 // GenerateSecMSGEC 生成 Sec-MS-GEC token
 func (d *DRM) GenerateSecMSGEC() string {
 	// 获取带时钟偏移校正的时间戳
@@ -82,7 +104,7 @@ func (d *DRM) GenerateSecMSGEC() string {
 	ticks *= SToNS / 100
 
 	// 创建要哈希的字符串
-	strToHash := fmt.Sprintf("%.0f%s", ticks, TrustedClientToken)
+	strToHash := fmt.Sprintf("%.0f%s", ticks, d.Identity().TrustedClientToken)
 
 	// 计算 SHA256 哈希并返回大写的十六进制
 	hash := sha256.Sum256([]byte(strToHash))
@@ -106,7 +128,27 @@ func HeadersWithMUID(headers map[string]string) map[string]string {
 	return combined
 }
 
-// GetDRM 获取全局 DRM 实例
+// cookie 按 MUID 策略返回 Cookie 请求头，MUIDNone 时返回空字符串
+func (d *DRM) cookie() string {
+	id := d.Identity()
+	switch id.MUIDStrategy {
+	case MUIDNone:
+		return ""
+	case MUIDPersistent:
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.muid == "" {
+			d.muid = id.MUID
+			if d.muid == "" {
+				d.muid = GenerateMUID()
+			}
+		}
+		return fmt.Sprintf("muid=%s;", d.muid)
+	}
+	return fmt.Sprintf("muid=%s;", GenerateMUID())
+}
+
+// GetDRM 获取全局 DRM 实例，未通过 Client.DRM 指定实例时使用
 func GetDRM() *DRM {
 	return globalDRM
 }
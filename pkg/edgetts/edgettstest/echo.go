@@ -0,0 +1,75 @@
+package edgettstest
+
+import (
+	"strings"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+)
+
+const (
+	// WordOffsetStep Echo 中相邻单词的偏移间隔（100 纳秒单位）
+	WordOffsetStep = 1_000_000
+	// WordDuration Echo 中每个单词的时长（100 纳秒单位）
+	WordDuration = 500_000
+)
+
+// Echo 默认的回合脚本：每个单词生成一帧音频（内容为单词本身），
+// 开启 WordBoundary 时每个单词一个边界，否则整个回合一个 SentenceBoundary
+func Echo(req *Request) Turn {
+	words := strings.Fields(req.Text)
+	turn := Turn{}
+	for i, w := range words {
+		turn.Audio = append(turn.Audio, []byte(w))
+		if req.WordBoundary {
+			turn.Boundaries = append(turn.Boundaries, Boundary{
+				Offset:   float64(i * WordOffsetStep),
+				Duration: WordDuration,
+				Text:     w,
+			})
+		}
+	}
+	if !req.WordBoundary && len(words) > 0 {
+		turn.Boundaries = append(turn.Boundaries, Boundary{
+			Offset:   0,
+			Duration: float64((len(words)-1)*WordOffsetStep + WordDuration),
+			Text:     strings.Join(words, " "),
+		})
+	}
+	return turn
+}
+
+// DefaultVoices 返回语音列表接口默认提供的语音
+func DefaultVoices() []edgetts.Voice {
+	mk := func(shortName, gender, locale, friendly string, categories, personalities []string) edgetts.Voice {
+		return edgetts.Voice{
+			Name:           "Microsoft Server Speech Text to Speech Voice (" + locale + ", " + strings.TrimPrefix(shortName, locale+"-") + ")",
+			ShortName:      shortName,
+			Gender:         gender,
+			Locale:         locale,
+			SuggestedCodec: "audio-24khz-48kbitrate-mono-mp3",
+			FriendlyName:   friendly,
+			Status:         "GA",
+			VoiceTag: edgetts.VoiceTag{
+				ContentCategories:  categories,
+				VoicePersonalities: personalities,
+			},
+		}
+	}
+	return []edgetts.Voice{
+		mk("en-US-EmmaMultilingualNeural", "Female", "en-US",
+			"Microsoft Emma Online (Natural) - English (United States)",
+			[]string{"Conversation", "Copilot"}, []string{"Cheerful", "Clear", "Conversational"}),
+		mk("en-US-GuyNeural", "Male", "en-US",
+			"Microsoft Guy Online (Natural) - English (United States)",
+			[]string{"News", "Novel"}, []string{"Passion"}),
+		mk("en-GB-SoniaNeural", "Female", "en-GB",
+			"Microsoft Sonia Online (Natural) - English (United Kingdom)",
+			[]string{"General"}, []string{"Friendly", "Positive"}),
+		mk("zh-CN-XiaoxiaoNeural", "Female", "zh-CN",
+			"Microsoft Xiaoxiao Online (Natural) - Chinese (Mainland)",
+			[]string{"News", "Novel"}, []string{"Warm"}),
+		mk("zh-HK-HiuGaaiNeural", "Female", "zh-HK",
+			"Microsoft HiuGaai Online (Natural) - Chinese (Cantonese Traditional)",
+			[]string{"General"}, []string{"Friendly", "Positive"}),
+	}
+}
@@ -0,0 +1,620 @@
+// Package edgettstest 提供进程内的 Edge TTS 模拟服务，用于离线测试
+//
+// Server 基于 httptest 实现 readaloud WebSocket 协议和 voices/list 接口，
+// 可以按脚本回放音频和边界元数据、注入协议错误，并校验 Sec-MS-GEC token。
+package edgettstest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// SynthesizePath WebSocket 合成接口路径
+	SynthesizePath = "/consumer/speech/synthesize/readaloud/edge/v1"
+	// VoiceListPath 语音列表接口路径
+	VoiceListPath = "/consumer/speech/synthesize/readaloud/voices/list"
+)
+
+// Fault 注入的故障类型
+type Fault int
+
+const (
+	// FaultNone 不注入故障
+	FaultNone Fault = iota
+	// FaultForbidden 握手和语音列表请求均返回 403
+	FaultForbidden
+	// FaultUnknownPath 回合中发送未知 Path 的文本消息
+	FaultUnknownPath
+	// FaultBinaryPath 二进制消息的 Path 不是 audio
+	FaultBinaryPath
+	// FaultTruncatedHeader 二进制消息声明的头部长度超过数据长度
+	FaultTruncatedHeader
+	// FaultMissingHeaderLength 二进制消息不足 2 字节
+	FaultMissingHeaderLength
+	// FaultContentType 音频的 Content-Type 与请求格式不符
+	FaultContentType
+	// FaultNoAudio 回合不包含音频
+	FaultNoAudio
+	// FaultCloseMidTurn 发送第一帧音频后直接关闭连接
+	FaultCloseMidTurn
+	// FaultBadMetadata 发送无法解析的元数据
+	FaultBadMetadata
+)
+
+// Boundary 边界元数据，Offset 和 Duration 以 100 纳秒为单位
+type Boundary struct {
+	Type     string // "WordBoundary" 或 "SentenceBoundary"，默认由请求决定
+	Offset   float64
+	Duration float64
+	Text     string
+}
+
+// Turn 一个合成回合的响应脚本
+// 第 i 个边界在第 i 帧音频之后发送，多余的边界在音频之后发送
+type Turn struct {
+	Audio      [][]byte
+	Boundaries []Boundary
+}
+
+// Request 服务端收到的一次合成请求
+type Request struct {
+	ConnectionID string
+	RequestID    string
+	Header       http.Header // 握手请求头
+	Query        url.Values  // 握手请求的查询参数
+	Config       string      // speech.config 消息体
+	OutputFormat string
+	WordBoundary bool
+	SSML         string
+	Text         string // SSML 中的纯文本
+}
+
+// Responder 根据请求生成回合脚本
+type Responder func(req *Request) Turn
+
+// Option 服务配置选项
+type Option func(*Server)
+
+// WithResponder 设置回合脚本生成函数，默认为 Echo
+func WithResponder(r Responder) Option {
+	return func(s *Server) {
+		s.responder = r
+	}
+}
+
+// WithTurns 依次回放固定的回合脚本，超出部分回放最后一个
+func WithTurns(turns ...Turn) Option {
+	return func(s *Server) {
+		var mu sync.Mutex
+		next := 0
+		s.responder = func(*Request) Turn {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(turns) == 0 {
+				return Turn{}
+			}
+			t := turns[next]
+			if next < len(turns)-1 {
+				next++
+			}
+			return t
+		}
+	}
+}
+
+// WithVoices 设置语音列表接口返回的语音
+func WithVoices(voices []edgetts.Voice) Option {
+	return func(s *Server) {
+		s.voices = voices
+	}
+}
+
+// WithVoiceListETag 语音列表响应带上 ETag，请求的 If-None-Match 与之相同时返回 304
+func WithVoiceListETag(etag string) Option {
+	return func(s *Server) {
+		s.voiceListETag = etag
+	}
+}
+
+// WithFault 注入故障
+func WithFault(f Fault) Option {
+	return func(s *Server) {
+		s.fault = f
+	}
+}
+
+// WithFrameDelay 每条响应消息发送前的延迟，用于模拟慢速服务
+func WithFrameDelay(d time.Duration) Option {
+	return func(s *Server) {
+		s.frameDelay = d
+	}
+}
+
+// WithClockSkew 设置服务端时钟相对本机的偏移，影响 token 校验和 Date 头
+func WithClockSkew(d time.Duration) Option {
+	return func(s *Server) {
+		s.clockSkew = d
+	}
+}
+
+// WithoutTokenCheck 关闭 Sec-MS-GEC 校验
+func WithoutTokenCheck() Option {
+	return func(s *Server) {
+		s.checkToken = false
+	}
+}
+
+// WithForbiddenHandshakes 前 n 次握手或语音列表请求返回 403
+func WithForbiddenHandshakes(n int) Option {
+	return func(s *Server) {
+		s.forbidden = n
+	}
+}
+
+// WithCloseAfterTurns 每个连接处理 n 个回合后由服务端关闭
+func WithCloseAfterTurns(n int) Option {
+	return func(s *Server) {
+		s.closeAfterTurns = n
+	}
+}
+
+// Server 模拟的 Edge TTS 服务
+type Server struct {
+	*httptest.Server
+
+	responder       Responder
+	voices          []edgetts.Voice
+	voiceListETag   string
+	fault           Fault
+	frameDelay      time.Duration
+	clockSkew       time.Duration
+	checkToken      bool
+	forbidden       int
+	closeAfterTurns int
+
+	mu          sync.Mutex
+	handshakes  int
+	rejected    int
+	notModified int
+	connections int
+	active      int
+	requests    []Request
+}
+
+// NewServer 启动模拟服务，调用方负责 Close
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		responder:  Echo,
+		voices:     DefaultVoices(),
+		checkToken: true,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(SynthesizePath, s.handleSynthesize)
+	mux.HandleFunc(VoiceListPath, s.handleVoiceList)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// WSSURL 返回 WebSocket 合成地址，TrustedClientToken 由客户端按客户端标识补充
+func (s *Server) WSSURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + SynthesizePath
+}
+
+// VoiceListURL 返回语音列表地址，trustedclienttoken 由客户端按客户端标识补充
+func (s *Server) VoiceListURL() string {
+	return s.URL + VoiceListPath
+}
+
+// Client 返回指向本服务的客户端
+func (s *Server) Client() *edgetts.Client {
+	return &edgetts.Client{
+		WSSURL:       s.WSSURL(),
+		VoiceListURL: s.VoiceListURL(),
+	}
+}
+
+// Handshakes 返回收到的握手和语音列表请求次数（含被拒绝的）
+func (s *Server) Handshakes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handshakes
+}
+
+// Rejected 返回被 403 拒绝的请求次数
+func (s *Server) Rejected() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rejected
+}
+
+// NotModified 返回语音列表返回 304 的次数
+func (s *Server) NotModified() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notModified
+}
+
+// Connections 返回成功建立的 WebSocket 连接数
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// ActiveConnections 返回当前仍未关闭的 WebSocket 连接数
+func (s *Server) ActiveConnections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+// Requests 返回收到的合成请求
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// now 返回服务端时间
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.clockSkew)
+}
+
+// authorize 校验请求，失败时写入 403 响应
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	s.handshakes++
+	reject := s.fault == FaultForbidden || s.handshakes <= s.forbidden
+	s.mu.Unlock()
+
+	if !reject && s.checkToken {
+		q := r.URL.Query()
+		token := q.Get("TrustedClientToken")
+		if token == "" {
+			token = q.Get("trustedclienttoken")
+		}
+		reject = token == "" || !validToken(q.Get("Sec-MS-GEC"), token, s.now())
+	}
+	if !reject {
+		return true
+	}
+
+	s.mu.Lock()
+	s.rejected++
+	s.mu.Unlock()
+	w.Header().Set("Date", s.now().UTC().Format(http.TimeFormat))
+	http.Error(w, "forbidden", http.StatusForbidden)
+	return false
+}
+
+func (s *Server) handleVoiceList(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r) {
+		return
+	}
+	if s.voiceListETag != "" {
+		w.Header().Set("ETag", s.voiceListETag)
+		if r.Header.Get("If-None-Match") == s.voiceListETag {
+			s.mu.Lock()
+			s.notModified++
+			s.mu.Unlock()
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.voices)
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+func (s *Server) handleSynthesize(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r) {
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.connections++
+	s.active++
+	s.mu.Unlock()
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		s.active--
+		s.mu.Unlock()
+	}()
+
+	sess := &session{
+		Server:       s,
+		conn:         conn,
+		connectionID: r.URL.Query().Get("ConnectionId"),
+		header:       r.Header.Clone(),
+		query:        r.URL.Query(),
+	}
+	sess.serve()
+}
+
+// session 一个 WebSocket 连接的状态
+type session struct {
+	*Server
+	conn         *websocket.Conn
+	connectionID string
+	header       http.Header
+	query        url.Values
+	config       string
+	turns        int
+}
+
+func (ss *session) serve() {
+	for {
+		msgType, data, err := ss.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if msgType != websocket.TextMessage {
+			continue
+		}
+
+		headers, body := splitTextMessage(data)
+		switch headers["Path"] {
+		case "speech.config":
+			ss.config = body
+		case "ssml":
+			req := ss.newRequest(headers["X-RequestId"], body)
+			ss.mu.Lock()
+			ss.requests = append(ss.requests, *req)
+			ss.mu.Unlock()
+
+			if !ss.playTurn(req, ss.responder(req)) {
+				return
+			}
+			ss.turns++
+			if ss.closeAfterTurns > 0 && ss.turns >= ss.closeAfterTurns {
+				ss.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+					time.Now().Add(time.Second))
+				return
+			}
+		}
+	}
+}
+
+// newRequest 解析 SSML 请求
+func (ss *session) newRequest(requestID, ssml string) *Request {
+	req := &Request{
+		ConnectionID: ss.connectionID,
+		RequestID:    requestID,
+		Header:       ss.header,
+		Query:        ss.query,
+		Config:       ss.config,
+		SSML:         ssml,
+		Text:         ssmlText(ssml),
+	}
+
+	var cfg struct {
+		Context struct {
+			Synthesis struct {
+				Audio struct {
+					MetadataOptions struct {
+						WordBoundaryEnabled string `json:"wordBoundaryEnabled"`
+					} `json:"metadataoptions"`
+					OutputFormat string `json:"outputFormat"`
+				} `json:"audio"`
+			} `json:"synthesis"`
+		} `json:"context"`
+	}
+	if json.Unmarshal([]byte(strings.TrimSpace(ss.config)), &cfg) == nil {
+		req.OutputFormat = cfg.Context.Synthesis.Audio.OutputFormat
+		req.WordBoundary = cfg.Context.Synthesis.Audio.MetadataOptions.WordBoundaryEnabled == "true"
+	}
+	return req
+}
+
+// playTurn 回放一个回合，返回 false 表示连接应关闭
+func (ss *session) playTurn(req *Request, turn Turn) bool {
+	rid := req.RequestID
+	boundaryType := "SentenceBoundary"
+	if req.WordBoundary {
+		boundaryType = "WordBoundary"
+	}
+	contentType := edgetts.OutputFormat(req.OutputFormat).MIMEType()
+	if ss.fault == FaultContentType {
+		contentType = "audio/x-bogus"
+	}
+	if ss.fault == FaultNoAudio {
+		turn.Audio = nil
+	}
+
+	if !ss.sendText(rid, "turn.start", `{"context":{"serviceTag":"edgettstest"}}`) {
+		return false
+	}
+	if ss.fault == FaultUnknownPath {
+		return ss.sendText(rid, "bogus.path", "{}")
+	}
+
+	sendBoundary := func(b Boundary) bool {
+		if b.Type == "" {
+			b.Type = boundaryType
+		}
+		if ss.fault == FaultBadMetadata {
+			return ss.sendText(rid, "audio.metadata", "{not json")
+		}
+		return ss.sendText(rid, "audio.metadata", metadataJSON(b))
+	}
+
+	for i, frame := range turn.Audio {
+		if !ss.sendAudio(rid, contentType, frame) {
+			return false
+		}
+		if ss.fault == FaultCloseMidTurn {
+			return false
+		}
+		if i < len(turn.Boundaries) && !sendBoundary(turn.Boundaries[i]) {
+			return false
+		}
+	}
+	for i := len(turn.Audio); i < len(turn.Boundaries); i++ {
+		if !sendBoundary(turn.Boundaries[i]) {
+			return false
+		}
+	}
+
+	// 服务端在回合结束前会发送一个不带 Content-Type 的空音频帧
+	if !ss.sendAudio(rid, "", nil) {
+		return false
+	}
+	return ss.sendText(rid, "turn.end", "{}")
+}
+
+func (ss *session) delay() {
+	if ss.frameDelay > 0 {
+		time.Sleep(ss.frameDelay)
+	}
+}
+
+func (ss *session) sendText(requestID, path, body string) bool {
+	ss.delay()
+	msg := "X-RequestId:" + requestID + "\r\n" +
+		"Content-Type:application/json; charset=utf-8\r\n" +
+		"Path:" + path + "\r\n\r\n" + body
+	return ss.conn.WriteMessage(websocket.TextMessage, []byte(msg)) == nil
+}
+
+func (ss *session) sendAudio(requestID, contentType string, data []byte) bool {
+	ss.delay()
+	path := "audio"
+	if ss.fault == FaultBinaryPath {
+		path = "video"
+	}
+
+	header := "X-RequestId:" + requestID + "\r\n"
+	if contentType != "" {
+		header += "Content-Type:" + contentType + "\r\n"
+	}
+	header += "Path:" + path + "\r\n"
+
+	var msg []byte
+	switch ss.fault {
+	case FaultMissingHeaderLength:
+		msg = []byte{0}
+	case FaultTruncatedHeader:
+		msg = binary.BigEndian.AppendUint16(nil, uint16(len(header)+len(data)+16))
+		msg = append(msg, header...)
+		msg = append(msg, data...)
+	default:
+		msg = binary.BigEndian.AppendUint16(nil, uint16(len(header)))
+		msg = append(msg, header...)
+		msg = append(msg, data...)
+	}
+	return ss.conn.WriteMessage(websocket.BinaryMessage, msg) == nil
+}
+
+// splitTextMessage 拆分文本消息的 headers 和 body
+func splitTextMessage(data []byte) (map[string]string, string) {
+	headers := make(map[string]string)
+	head, body, _ := bytes.Cut(data, []byte("\r\n\r\n"))
+	for _, line := range bytes.Split(head, []byte("\r\n")) {
+		if k, v, ok := bytes.Cut(line, []byte(":")); ok {
+			headers[string(k)] = string(v)
+		}
+	}
+	return headers, string(body)
+}
+
+// metadataJSON 构建 audio.metadata 消息体
+func metadataJSON(b Boundary) string {
+	type text struct {
+		Text         string `json:"Text"`
+		Length       int    `json:"Length"`
+		BoundaryType string `json:"BoundaryType"`
+	}
+	type data struct {
+		Offset   float64 `json:"Offset"`
+		Duration float64 `json:"Duration"`
+		Text     text    `json:"text"`
+	}
+	type meta struct {
+		Type string `json:"Type"`
+		Data data   `json:"Data"`
+	}
+	out, _ := json.Marshal(struct {
+		Metadata []meta `json:"Metadata"`
+	}{[]meta{{
+		Type: b.Type,
+		Data: data{
+			Offset:   b.Offset,
+			Duration: b.Duration,
+			Text:     text{Text: b.Text, Length: len(b.Text), BoundaryType: b.Type},
+		},
+	}}})
+	return string(out)
+}
+
+// ssmlText 提取 SSML 中的纯文本
+func ssmlText(ssml string) string {
+	dec := xml.NewDecoder(strings.NewReader(ssml))
+	var sb strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF || err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.StartElement:
+			// 停顿两侧是不同的词
+			if t.Name.Local == "break" {
+				sb.WriteByte(' ')
+			}
+		}
+	}
+	return sb.String()
+}
+
+// validToken 按 TrustedClientToken 校验 Sec-MS-GEC，允许相邻的 5 分钟窗口以避免边界抖动
+func validToken(gec, clientToken string, now time.Time) bool {
+	for _, d := range []time.Duration{0, -5 * time.Minute, 5 * time.Minute} {
+		if gec == secMSGEC(now.Add(d), clientToken) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecMSGEC 计算给定时间使用默认 TrustedClientToken 的 Sec-MS-GEC token
+func SecMSGEC(t time.Time) string {
+	return secMSGEC(t, edgetts.TrustedClientToken)
+}
+
+func secMSGEC(t time.Time, clientToken string) string {
+	ticks := t.Unix() + edgetts.WinEpoch
+	ticks -= ticks % 300
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d%s", ticks*10_000_000, clientToken)))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -1,6 +1,13 @@
 package edgetts
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
 
 var (
 	// ErrUnknownResponse 收到未知响应
@@ -30,6 +37,166 @@ var (
 	// ErrInvalidPitch 无效的音调
 	ErrInvalidPitch = errors.New("invalid pitch format")
 
+	// ErrInvalidOutputFormat 无效的输出格式
+	ErrInvalidOutputFormat = errors.New("invalid output format")
+
+	// ErrInvalidProxy 无效的代理地址
+	ErrInvalidProxy = errors.New("invalid proxy")
+
+	// ErrInvalidStyle 无效的说话风格
+	ErrInvalidStyle = errors.New("invalid speaking style")
+
+	// ErrStyleDropped 服务端不支持说话风格，已去掉风格重新合成
+	ErrStyleDropped = errors.New("speaking style not supported by endpoint, synthesized without it")
+
+	// ErrInvalidRole 无效的角色
+	ErrInvalidRole = errors.New("invalid role")
+
+	// ErrInvalidChunkSize 无效的文本块大小
+	ErrInvalidChunkSize = errors.New("invalid chunk size")
+
+	// ErrInvalidSSML 无效的 SSML
+	ErrInvalidSSML = errors.New("invalid ssml")
+
+	// ErrInvalidInputFormat 无效的输入格式
+	ErrInvalidInputFormat = errors.New("invalid input format")
+
+	// ErrInvalidIdentity 无效的客户端标识
+	ErrInvalidIdentity = errors.New("invalid client identity")
+
+	// ErrNoVoiceMatch 没有符合查询条件的语音
+	ErrNoVoiceMatch = errors.New("no voice matches the query")
+
 	// ErrStreamAlreadyCalled stream 已经被调用
 	ErrStreamAlreadyCalled = errors.New("stream can only be called once")
+
+	// ErrClosed Communicate 已经关闭
+	ErrClosed = errors.New("communicate is closed")
 )
+
+// maxErrorBody 错误中保留的响应体最大字节数
+const maxErrorBody = 1024
+
+// HandshakeError.Op 的取值
+const (
+	opWebSocketHandshake = "websocket handshake"
+	opListVoices         = "list voices"
+)
+
+// HandshakeError WebSocket 握手或语音列表请求被服务端以非预期的 HTTP 状态拒绝
+type HandshakeError struct {
+	Op         string    // "websocket handshake" 或 "list voices"
+	StatusCode int       // HTTP 状态码
+	Date       time.Time // 服务端 Date 头，没有或无法解析时为零值
+	Header     http.Header
+	Body       string // 响应体，最多保留 1024 字节
+	Err        error  // 底层错误，可能为 nil
+}
+
+// newHandshakeError 从响应构造 HandshakeError，会读取但不关闭响应体
+func newHandshakeError(op string, resp *http.Response, err error) *HandshakeError {
+	e := &HandshakeError{Op: op, StatusCode: resp.StatusCode, Header: resp.Header, Err: err}
+	if date, perr := http.ParseTime(resp.Header.Get("Date")); perr == nil {
+		e.Date = date
+	}
+	if resp.Body != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		e.Body = strings.TrimSpace(string(body))
+	}
+	return e
+}
+
+func (e *HandshakeError) Error() string {
+	msg := fmt.Sprintf("%s: status %d", e.Op, e.StatusCode)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// Is WebSocket 握手错误匹配 ErrWebSocket
+func (e *HandshakeError) Is(target error) bool {
+	return target == ErrWebSocket && e.Op == opWebSocketHandshake
+}
+
+// Retryable 403（时钟偏移）、408、429 和 5xx 可以重试
+func (e *HandshakeError) Retryable() bool {
+	switch code := e.StatusCode; {
+	case code == http.StatusForbidden,
+		code == http.StatusRequestTimeout,
+		code == http.StatusTooManyRequests,
+		code >= 500:
+		return true
+	}
+	return false
+}
+
+// Temporary 同 Retryable
+func (e *HandshakeError) Temporary() bool {
+	return e.Retryable()
+}
+
+// ProtocolError 服务端返回的消息不符合协议，Err 匹配 ErrUnexpectedResponse、ErrUnknownResponse 或 ErrNoAudioReceived
+type ProtocolError struct {
+	Path   string            // 消息的 Path 头，二进制消息缺少头部时为空
+	Header map[string]string // 消息的全部头部
+	Frame  []byte            // 出错的原始消息
+	Err    error
+}
+
+func (e *ProtocolError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (path %s)", e.Err, e.Path)
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 协议错误重试也会得到同样的结果，不可重试
+func (e *ProtocolError) Retryable() bool {
+	return false
+}
+
+// Temporary 同 Retryable
+func (e *ProtocolError) Temporary() bool {
+	return false
+}
+
+// ChunkError 合成某个文本块时出错
+type ChunkError struct {
+	Index      int    // 文本块序号，从 0 开始
+	Start, End int    // 文本块在转义后的输入文本中的字节范围，SSML、对话和文档输入为 -1
+	RequestID  string // 最后一次发送该文本块的 X-RequestId，没有发送时为空
+	Err        error
+}
+
+func (e *ChunkError) Error() string {
+	if e.RequestID == "" {
+		return fmt.Sprintf("chunk %d: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("chunk %d (request %s): %v", e.Index, e.RequestID, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 由底层错误决定
+func (e *ChunkError) Retryable() bool {
+	return IsRetryable(e.Err)
+}
+
+// Temporary 同 Retryable
+func (e *ChunkError) Temporary() bool {
+	return e.Retryable()
+}
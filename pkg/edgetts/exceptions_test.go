@@ -0,0 +1,89 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+func TestHandshakeError(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFault(edgettstest.FaultForbidden))
+	defer srv.Close()
+
+	_, _, err := synthesize(t, srv, "hello", edgetts.WithRetry(edgetts.NoRetry))
+	var he *edgetts.HandshakeError
+	if !errors.As(err, &he) {
+		t.Fatalf("err = %v, want HandshakeError", err)
+	}
+	if he.StatusCode != 403 || he.Body != "forbidden" || he.Date.IsZero() {
+		t.Errorf("status = %d, body = %q, date = %v", he.StatusCode, he.Body, he.Date)
+	}
+	if !he.Retryable() || !edgetts.IsRetryable(err) || !errors.Is(err, edgetts.ErrWebSocket) {
+		t.Error("403 handshake should be retryable and match ErrWebSocket")
+	}
+	var ce *edgetts.ChunkError
+	if !errors.As(err, &ce) || ce.Index != 0 || ce.RequestID != "" {
+		t.Errorf("chunk error = %+v", ce)
+	}
+
+	_, err = edgetts.ListVoices(context.Background(), &edgetts.ListVoicesOptions{
+		Client: srv.Client(),
+		Retry:  &edgetts.NoRetry,
+	})
+	if !errors.As(err, &he) || he.StatusCode != 403 || errors.Is(err, edgetts.ErrWebSocket) {
+		t.Errorf("list voices err = %v", err)
+	}
+}
+
+func TestProtocolError(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFault(edgettstest.FaultUnknownPath))
+	defer srv.Close()
+
+	_, _, err := synthesize(t, srv, "hello world", edgetts.WithRetry(edgetts.NoRetry))
+	var pe *edgetts.ProtocolError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v, want ProtocolError", err)
+	}
+	if pe.Path == "" || pe.Header["Path"] != pe.Path || len(pe.Frame) == 0 {
+		t.Errorf("protocol error = %+v", pe)
+	}
+	if !errors.Is(err, edgetts.ErrUnknownResponse) || edgetts.IsRetryable(err) || pe.Temporary() {
+		t.Error("protocol error should match ErrUnknownResponse and not be retryable")
+	}
+
+	var ce *edgetts.ChunkError
+	if !errors.As(err, &ce) {
+		t.Fatalf("err = %v, want ChunkError", err)
+	}
+	if ce.Index != 0 || ce.Start != 0 || ce.End != len("hello world") {
+		t.Errorf("chunk %d range [%d, %d)", ce.Index, ce.Start, ce.End)
+	}
+	if want := srv.Requests()[0].RequestID; ce.RequestID != want {
+		t.Errorf("request id = %q, want %q", ce.RequestID, want)
+	}
+}
+
+func TestChunkErrorIndex(t *testing.T) {
+	// 第二个回合没有音频
+	srv := edgettstest.NewServer(edgettstest.WithTurns(
+		edgettstest.Turn{Audio: [][]byte{[]byte("first")}},
+		edgettstest.Turn{},
+	))
+	defer srv.Close()
+
+	text := "First sentence here. Second sentence here."
+	_, _, err := synthesize(t, srv, text, edgetts.WithChunkSize(24))
+	var ce *edgetts.ChunkError
+	if !errors.As(err, &ce) || !errors.Is(err, edgetts.ErrNoAudioReceived) {
+		t.Fatalf("err = %v, want ChunkError with ErrNoAudioReceived", err)
+	}
+	if ce.Index != 1 || text[ce.Start:ce.End] != "Second sentence here." {
+		t.Errorf("chunk %d range [%d, %d)", ce.Index, ce.Start, ce.End)
+	}
+	if want := srv.Requests()[1].RequestID; ce.RequestID != want {
+		t.Errorf("request id = %q, want %q", ce.RequestID, want)
+	}
+}
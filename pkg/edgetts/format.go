@@ -0,0 +1,186 @@
+package edgetts
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// OutputFormat 音频输出格式（speech.config 中的 outputFormat）
+type OutputFormat string
+
+// 服务支持的输出格式
+const (
+	// MP3
+	Audio16khz32kbitrateMonoMP3  OutputFormat = "audio-16khz-32kbitrate-mono-mp3"
+	Audio16khz64kbitrateMonoMP3  OutputFormat = "audio-16khz-64kbitrate-mono-mp3"
+	Audio16khz128kbitrateMonoMP3 OutputFormat = "audio-16khz-128kbitrate-mono-mp3"
+	Audio24khz48kbitrateMonoMP3  OutputFormat = "audio-24khz-48kbitrate-mono-mp3"
+	Audio24khz96kbitrateMonoMP3  OutputFormat = "audio-24khz-96kbitrate-mono-mp3"
+	Audio24khz160kbitrateMonoMP3 OutputFormat = "audio-24khz-160kbitrate-mono-mp3"
+	Audio48khz96kbitrateMonoMP3  OutputFormat = "audio-48khz-96kbitrate-mono-mp3"
+	Audio48khz192kbitrateMonoMP3 OutputFormat = "audio-48khz-192kbitrate-mono-mp3"
+
+	// Opus
+	Webm24khz16bitMonoOpus OutputFormat = "webm-24khz-16bit-mono-opus"
+	Webm16khz16bitMonoOpus OutputFormat = "webm-16khz-16bit-mono-opus"
+	Ogg16khz16bitMonoOpus  OutputFormat = "ogg-16khz-16bit-mono-opus"
+	Ogg24khz16bitMonoOpus  OutputFormat = "ogg-24khz-16bit-mono-opus"
+	Ogg48khz16bitMonoOpus  OutputFormat = "ogg-48khz-16bit-mono-opus"
+
+	// 无头 PCM
+	Raw8khz16bitMonoPCM    OutputFormat = "raw-8khz-16bit-mono-pcm"
+	Raw16khz16bitMonoPCM   OutputFormat = "raw-16khz-16bit-mono-pcm"
+	Raw22050hz16bitMonoPCM OutputFormat = "raw-22050hz-16bit-mono-pcm"
+	Raw24khz16bitMonoPCM   OutputFormat = "raw-24khz-16bit-mono-pcm"
+	Raw44100hz16bitMonoPCM OutputFormat = "raw-44100hz-16bit-mono-pcm"
+	Raw48khz16bitMonoPCM   OutputFormat = "raw-48khz-16bit-mono-pcm"
+
+	// RIFF WAV
+	Riff8khz16bitMonoPCM    OutputFormat = "riff-8khz-16bit-mono-pcm"
+	Riff16khz16bitMonoPCM   OutputFormat = "riff-16khz-16bit-mono-pcm"
+	Riff22050hz16bitMonoPCM OutputFormat = "riff-22050hz-16bit-mono-pcm"
+	Riff24khz16bitMonoPCM   OutputFormat = "riff-24khz-16bit-mono-pcm"
+	Riff44100hz16bitMonoPCM OutputFormat = "riff-44100hz-16bit-mono-pcm"
+	Riff48khz16bitMonoPCM   OutputFormat = "riff-48khz-16bit-mono-pcm"
+
+	// DefaultOutputFormat 默认输出格式
+	DefaultOutputFormat = Audio24khz48kbitrateMonoMP3
+)
+
+// formatInfo 格式元信息
+type formatInfo struct {
+	mimeType     string
+	extension    string
+	contentTypes []string // 服务端可能返回的 Content-Type（不含参数）
+}
+
+var (
+	mp3Info  = formatInfo{"audio/mpeg", ".mp3", []string{"audio/mpeg"}}
+	webmInfo = formatInfo{"audio/webm", ".webm", []string{"audio/webm"}}
+	oggInfo  = formatInfo{"audio/ogg", ".ogg", []string{"audio/ogg", "audio/opus"}}
+	pcmInfo  = formatInfo{"audio/L16", ".pcm", []string{"audio/pcm", "audio/x-pcm", "audio/l16", "audio/basic"}}
+	riffInfo = formatInfo{"audio/wav", ".wav", []string{"audio/wav", "audio/x-wav", "audio/wave"}}
+)
+
+// outputFormats 已知格式表
+var outputFormats = map[OutputFormat]formatInfo{
+	Audio16khz32kbitrateMonoMP3:  mp3Info,
+	Audio16khz64kbitrateMonoMP3:  mp3Info,
+	Audio16khz128kbitrateMonoMP3: mp3Info,
+	Audio24khz48kbitrateMonoMP3:  mp3Info,
+	Audio24khz96kbitrateMonoMP3:  mp3Info,
+	Audio24khz160kbitrateMonoMP3: mp3Info,
+	Audio48khz96kbitrateMonoMP3:  mp3Info,
+	Audio48khz192kbitrateMonoMP3: mp3Info,
+
+	Webm24khz16bitMonoOpus: webmInfo,
+	Webm16khz16bitMonoOpus: webmInfo,
+	Ogg16khz16bitMonoOpus:  oggInfo,
+	Ogg24khz16bitMonoOpus:  oggInfo,
+	Ogg48khz16bitMonoOpus:  oggInfo,
+
+	Raw8khz16bitMonoPCM:    pcmInfo,
+	Raw16khz16bitMonoPCM:   pcmInfo,
+	Raw22050hz16bitMonoPCM: pcmInfo,
+	Raw24khz16bitMonoPCM:   pcmInfo,
+	Raw44100hz16bitMonoPCM: pcmInfo,
+	Raw48khz16bitMonoPCM:   pcmInfo,
+
+	Riff8khz16bitMonoPCM:    riffInfo,
+	Riff16khz16bitMonoPCM:   riffInfo,
+	Riff22050hz16bitMonoPCM: riffInfo,
+	Riff24khz16bitMonoPCM:   riffInfo,
+	Riff44100hz16bitMonoPCM: riffInfo,
+	Riff48khz16bitMonoPCM:   riffInfo,
+}
+
+// OutputFormats 返回所有已知的输出格式
+func OutputFormats() []OutputFormat {
+	return []OutputFormat{
+		Audio16khz32kbitrateMonoMP3, Audio16khz64kbitrateMonoMP3, Audio16khz128kbitrateMonoMP3,
+		Audio24khz48kbitrateMonoMP3, Audio24khz96kbitrateMonoMP3, Audio24khz160kbitrateMonoMP3,
+		Audio48khz96kbitrateMonoMP3, Audio48khz192kbitrateMonoMP3,
+		Webm24khz16bitMonoOpus, Webm16khz16bitMonoOpus,
+		Ogg16khz16bitMonoOpus, Ogg24khz16bitMonoOpus, Ogg48khz16bitMonoOpus,
+		Raw8khz16bitMonoPCM, Raw16khz16bitMonoPCM, Raw22050hz16bitMonoPCM,
+		Raw24khz16bitMonoPCM, Raw44100hz16bitMonoPCM, Raw48khz16bitMonoPCM,
+		Riff8khz16bitMonoPCM, Riff16khz16bitMonoPCM, Riff22050hz16bitMonoPCM,
+		Riff24khz16bitMonoPCM, Riff44100hz16bitMonoPCM, Riff48khz16bitMonoPCM,
+	}
+}
+
+// ParseOutputFormat 解析输出格式字符串
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	f := OutputFormat(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := outputFormats[f]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidOutputFormat, s)
+	}
+	return f, nil
+}
+
+// OutputFormatForExtension 根据文件扩展名返回默认输出格式
+func OutputFormatForExtension(ext string) (OutputFormat, bool) {
+	switch strings.ToLower(ext) {
+	case ".mp3":
+		return Audio24khz48kbitrateMonoMP3, true
+	case ".webm":
+		return Webm24khz16bitMonoOpus, true
+	case ".ogg", ".opus":
+		return Ogg24khz16bitMonoOpus, true
+	case ".pcm", ".raw":
+		return Raw24khz16bitMonoPCM, true
+	case ".wav":
+		return Riff24khz16bitMonoPCM, true
+	}
+	return "", false
+}
+
+// Valid 判断格式是否已知
+func (f OutputFormat) Valid() bool {
+	_, ok := outputFormats[f]
+	return ok
+}
+
+// MIMEType 返回格式对应的 MIME 类型
+func (f OutputFormat) MIMEType() string {
+	if info, ok := outputFormats[f]; ok {
+		return info.mimeType
+	}
+	return "application/octet-stream"
+}
+
+// Extension 返回格式对应的文件扩展名（包含点）
+func (f OutputFormat) Extension() string {
+	if info, ok := outputFormats[f]; ok {
+		return info.extension
+	}
+	return ".bin"
+}
+
+// String 返回格式字符串
+func (f OutputFormat) String() string {
+	return string(f)
+}
+
+// acceptsContentType 判断服务端返回的 Content-Type 是否与格式匹配
+func (f OutputFormat) acceptsContentType(contentType string) bool {
+	info, ok := outputFormats[f]
+	if !ok {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(contentType)
+	}
+	mediaType = strings.ToLower(mediaType)
+	if mediaType == strings.ToLower(info.mimeType) {
+		return true
+	}
+	for _, ct := range info.contentTypes {
+		if mediaType == ct {
+			return true
+		}
+	}
+	return false
+}
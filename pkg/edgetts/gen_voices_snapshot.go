@@ -0,0 +1,38 @@
+//go:build ignore
+
+// gen_voices_snapshot 从服务端获取语音列表，写入 voices_snapshot.json
+// 用法：go generate ./pkg/edgetts
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+)
+
+func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	voices, err := edgetts.ListVoices(ctx, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Slice(voices, func(i, j int) bool {
+		return voices[i].ShortName < voices[j].ShortName
+	})
+
+	data, err := json.MarshalIndent(voices, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("voices_snapshot.json", append(data, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d voices", len(voices))
+}
@@ -0,0 +1,104 @@
+package edgetts
+
+import "time"
+
+// Hooks 合成过程的回调，用于进度显示、指标采集和调试，未设置的回调不会被调用
+// 回调在合成的 goroutine 中同步执行，不应阻塞；并行模式下可能被并发调用
+type Hooks struct {
+	// OnDialStart 每次尝试 WebSocket 握手前调用
+	OnDialStart func(DialInfo)
+	// OnDialDone 每次握手结束后调用，包含耗时、HTTP 状态码和错误
+	OnDialDone func(DialInfo)
+	// OnConfigSent 在新连接上发送 speech.config 后调用
+	OnConfigSent func(config string)
+	// OnSSMLSent 发送一个文本块的 SSML 后调用
+	OnSSMLSent func(TurnInfo)
+	// OnTurnStart 收到 turn.start 时调用，Elapsed 即首包延迟
+	OnTurnStart func(TurnInfo)
+	// OnAudio 收到每一帧音频时调用
+	OnAudio func(info TurnInfo, data []byte)
+	// OnMetadata 收到每条边界元数据时调用，偏移为回合内的原始值
+	OnMetadata func(info TurnInfo, boundary TTSChunk)
+	// OnTurnEnd 收到 turn.end 时调用
+	OnTurnEnd func(TurnInfo)
+	// OnRetry 握手失败后重试或连接断开后重连前调用
+	OnRetry func(RetryInfo)
+	// OnError 已开始的合成因错误（包括 ctx 取消）结束时调用一次
+	// Stream 因 ErrClosed 或 ErrStreamAlreadyCalled 没有开始合成时不调用，错误只通过 Stream 返回
+	OnError func(error)
+}
+
+// DialInfo 一次握手尝试
+type DialInfo struct {
+	Attempt    int // 从 1 开始
+	Start      time.Time
+	Duration   time.Duration // 仅 OnDialDone
+	StatusCode int           // 握手响应的 HTTP 状态码，没有收到响应时为 0；仅 OnDialDone
+	Err        error         // 仅 OnDialDone
+}
+
+// TurnInfo 一个文本块的合成回合
+type TurnInfo struct {
+	Chunk     int // 文本块序号，从 0 开始
+	Chunks    int // 文本块总数
+	RequestID string
+	SSML      string
+	Start     time.Time     // 发送 SSML 的时间
+	Elapsed   time.Duration // 从发送 SSML 到当前事件的时间
+}
+
+// RetryInfo 一次重试
+type RetryInfo struct {
+	Attempt   int  // 即将进行的尝试次数，从 2 开始
+	Reconnect bool // true 表示回合中连接断开后重连，false 表示握手重试
+	Err       error
+	Wait      time.Duration // 重试前的等待时间
+}
+
+// WithHooks 设置合成过程的回调
+func WithHooks(hooks Hooks) CommunicateOption {
+	return func(c *Communicate) {
+		c.hooks = hooks
+	}
+}
+
+// withDefaults 把未设置的回调替换为空函数，调用方无需判空
+func (h Hooks) withDefaults() Hooks {
+	if h.OnDialStart == nil {
+		h.OnDialStart = func(DialInfo) {}
+	}
+	if h.OnDialDone == nil {
+		h.OnDialDone = func(DialInfo) {}
+	}
+	if h.OnConfigSent == nil {
+		h.OnConfigSent = func(string) {}
+	}
+	if h.OnSSMLSent == nil {
+		h.OnSSMLSent = func(TurnInfo) {}
+	}
+	if h.OnTurnStart == nil {
+		h.OnTurnStart = func(TurnInfo) {}
+	}
+	if h.OnAudio == nil {
+		h.OnAudio = func(TurnInfo, []byte) {}
+	}
+	if h.OnMetadata == nil {
+		h.OnMetadata = func(TurnInfo, TTSChunk) {}
+	}
+	if h.OnTurnEnd == nil {
+		h.OnTurnEnd = func(TurnInfo) {}
+	}
+	if h.OnRetry == nil {
+		h.OnRetry = func(RetryInfo) {}
+	}
+	if h.OnError == nil {
+		h.OnError = func(error) {}
+	}
+	return h
+}
+
+// elapsed 返回更新了 Elapsed 的副本
+func (t TurnInfo) elapsed() TurnInfo {
+	t.Elapsed = time.Since(t.Start)
+	return t
+}
@@ -0,0 +1,100 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+// recorder 记录回调事件
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.events, " ")
+}
+
+func (r *recorder) hooks() edgetts.Hooks {
+	return edgetts.Hooks{
+		OnDialStart:  func(d edgetts.DialInfo) { r.add("dial%d", d.Attempt) },
+		OnDialDone:   func(d edgetts.DialInfo) { r.add("dialed%d:%d", d.Attempt, d.StatusCode) },
+		OnConfigSent: func(string) { r.add("config") },
+		OnSSMLSent:   func(t edgetts.TurnInfo) { r.add("ssml%d/%d", t.Chunk, t.Chunks) },
+		OnTurnStart:  func(t edgetts.TurnInfo) { r.add("start%d", t.Chunk) },
+		OnAudio:      func(t edgetts.TurnInfo, data []byte) { r.add("audio:%s", data) },
+		OnMetadata:   func(t edgetts.TurnInfo, b edgetts.TTSChunk) { r.add("meta:%s", b.Text) },
+		OnTurnEnd:    func(t edgetts.TurnInfo) { r.add("end%d", t.Chunk) },
+		OnRetry:      func(ri edgetts.RetryInfo) { r.add("retry%d", ri.Attempt) },
+		OnError:      func(err error) { r.add("error") },
+	}
+}
+
+func TestHooksSequence(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithForbiddenHandshakes(1))
+	defer srv.Close()
+
+	rec := &recorder{}
+	_, _, err := synthesize(t, srv, "One. Two.",
+		edgetts.WithChunkSize(5),
+		edgetts.WithBoundary("WordBoundary"),
+		edgetts.WithRetry(fastRetry),
+		edgetts.WithHooks(rec.hooks()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "dial1 dialed1:403 retry2 dial2 dialed2:101 config " +
+		"ssml0/2 start0 audio:One. meta:One. end0 " +
+		"ssml1/2 start1 audio:Two. meta:Two. end1"
+	if got := rec.String(); got != want {
+		t.Errorf("events:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestHooksError(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFault(edgettstest.FaultNoAudio))
+	defer srv.Close()
+
+	var got error
+	_, _, err := synthesize(t, srv, "hello", edgetts.WithHooks(edgetts.Hooks{
+		OnError: func(err error) { got = err },
+	}))
+	if err == nil || !errors.Is(got, edgetts.ErrNoAudioReceived) {
+		t.Errorf("OnError got %v, stream returned %v", got, err)
+	}
+}
+
+func TestHooksErrorNotCalledBeforeStart(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	calls := 0
+	comm, err := edgetts.NewCommunicate("hello", "", edgetts.WithClient(srv.Client()),
+		edgetts.WithHooks(edgetts.Hooks{OnError: func(error) { calls++ }}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	comm.Close()
+	if _, err := comm.StreamSync(context.Background()); !errors.Is(err, edgetts.ErrClosed) {
+		t.Fatalf("err = %v, want ErrClosed", err)
+	}
+	if calls != 0 {
+		t.Errorf("OnError called %d times before synthesis started", calls)
+	}
+}
@@ -0,0 +1,146 @@
+package edgetts
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skippedHTMLElements 内容不朗读的元素
+var skippedHTMLElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Svg: true, atom.Math: true, atom.Iframe: true, atom.Object: true,
+}
+
+// headingHTMLElements 标题元素
+var headingHTMLElements = map[atom.Atom]bool{
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+// itemHTMLElements 前后使用 ItemPause 的元素
+var itemHTMLElements = map[atom.Atom]bool{
+	atom.Li: true, atom.Dt: true, atom.Dd: true, atom.Tr: true, atom.Br: true, atom.Option: true,
+}
+
+// blockHTMLElements 前后使用 ParagraphPause 的块级元素
+var blockHTMLElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Aside: true,
+	atom.Header: true, atom.Footer: true, atom.Main: true, atom.Nav: true, atom.Blockquote: true,
+	atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Table: true, atom.Caption: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Hr: true, atom.Address: true,
+	atom.Form: true, atom.Fieldset: true, atom.Details: true, atom.Summary: true,
+}
+
+// htmlEntity HTML 字符引用
+var htmlEntity = regexp.MustCompile(`&(?:#[0-9]+|#[xX][0-9a-fA-F]+|[A-Za-z][A-Za-z0-9]*);?`)
+
+// parseHTML 把 HTML 文档转换为朗读文本
+// <head>、<script>、<style> 等元素被丢弃，链接只朗读文本，图片朗读 alt，<pre> 按代码块处理
+func parseHTML(src string, opts DocumentOptions) []docSegment {
+	b := &docBuilder{opts: opts}
+	z := nethtml.NewTokenizer(strings.NewReader(src))
+
+	pos := 0
+	// skipping 为正在跳过的元素，skipDepth 是其嵌套层数，skipStart 是其开始位置
+	var skipping atom.Atom
+	skipDepth, skipStart := 0, 0
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			break
+		}
+		start := pos
+		raw := string(z.Raw())
+		pos += len(raw)
+
+		var a atom.Atom
+		if tt == nethtml.StartTagToken || tt == nethtml.EndTagToken || tt == nethtml.SelfClosingTagToken {
+			name, _ := z.TagName()
+			a = atom.Lookup(name)
+		}
+
+		if skipDepth > 0 {
+			switch {
+			case tt == nethtml.StartTagToken && a == skipping:
+				skipDepth++
+			case tt == nethtml.EndTagToken && a == skipping:
+				skipDepth--
+				if skipDepth == 0 && a == atom.Pre {
+					b.codeBlock(src, skipStart, pos, pos, pos)
+				}
+			}
+			continue
+		}
+
+		switch tt {
+		case nethtml.TextToken:
+			b.htmlText(raw, start)
+
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			switch {
+			case skippedHTMLElements[a]:
+				if tt == nethtml.StartTagToken {
+					skipping, skipDepth = a, 1
+				}
+			case a == atom.Pre:
+				if opts.CodeBlocks == CodeRead {
+					b.pause(opts.ParagraphPause)
+				} else if tt == nethtml.StartTagToken {
+					skipping, skipDepth, skipStart = a, 1, start
+				}
+			case a == atom.Img:
+				b.spoken(htmlAttr(z, "alt"), start, pos)
+			case headingHTMLElements[a]:
+				b.pause(opts.ParagraphPause)
+				b.emphasis = opts.EmphasizeHeadings
+			case itemHTMLElements[a]:
+				b.pause(opts.ItemPause)
+			case a == atom.Td || a == atom.Th:
+				b.pause(opts.ItemPause)
+			case blockHTMLElements[a]:
+				b.pause(opts.ParagraphPause)
+			}
+
+		case nethtml.EndTagToken:
+			switch {
+			case a == atom.Pre:
+				b.pause(opts.ParagraphPause)
+			case headingHTMLElements[a]:
+				b.emphasis = false
+				b.pause(opts.HeadingPause)
+			case itemHTMLElements[a]:
+				b.pause(opts.ItemPause)
+			case blockHTMLElements[a]:
+				b.pause(opts.ParagraphPause)
+			}
+		}
+	}
+	return b.segs
+}
+
+// htmlText 添加源文档 start 处的文本节点，字符引用解码后朗读，其余部分逐字节对应
+func (b *docBuilder) htmlText(raw string, start int) {
+	pos := 0
+	for _, loc := range htmlEntity.FindAllStringIndex(raw, -1) {
+		b.text(raw[pos:loc[0]], start+pos)
+		b.spoken(html.UnescapeString(raw[loc[0]:loc[1]]), start+loc[0], start+loc[1])
+		pos = loc[1]
+	}
+	b.text(raw[pos:], start+pos)
+}
+
+// htmlAttr 返回当前标签的属性值
+func htmlAttr(z *nethtml.Tokenizer, name string) string {
+	for {
+		key, val, more := z.TagAttr()
+		if string(key) == name {
+			return string(val)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
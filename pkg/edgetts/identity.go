@@ -0,0 +1,212 @@
+package edgetts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MUIDStrategy 握手请求中 muid cookie 的生成方式
+type MUIDStrategy string
+
+const (
+	// MUIDRandom 每次请求生成新的 MUID（默认）
+	MUIDRandom MUIDStrategy = "random"
+	// MUIDPersistent 同一个 DRM 实例的所有请求使用同一个 MUID
+	MUIDPersistent MUIDStrategy = "persistent"
+	// MUIDNone 不发送 muid cookie
+	MUIDNone MUIDStrategy = "none"
+)
+
+// ClientIdentity 客户端标识：模拟的 Edge 版本、User-Agent、Origin、TrustedClientToken 和 MUID 策略
+// 服务端要求的 Edge 版本变化时，不必等待新版本发布，覆盖 ChromiumVersion 即可
+// 空字段使用默认值，UserAgent 默认按 ChromiumVersion 的主版本号生成
+type ClientIdentity struct {
+	// ChromiumVersion Edge 完整版本号，例如 143.0.3650.75，决定 Sec-MS-GEC-Version
+	ChromiumVersion string `json:"chromium_version,omitempty"`
+	// UserAgent 请求的 User-Agent
+	UserAgent string `json:"user_agent,omitempty"`
+	// Origin WebSocket 握手的 Origin
+	Origin string `json:"origin,omitempty"`
+	// TrustedClientToken 地址中的 TrustedClientToken，也参与 Sec-MS-GEC 的计算
+	TrustedClientToken string `json:"trusted_client_token,omitempty"`
+	// MUIDStrategy muid cookie 的生成方式，默认为 MUIDRandom
+	MUIDStrategy MUIDStrategy `json:"muid_strategy,omitempty"`
+	// MUID MUIDPersistent 使用的 32 位十六进制值，为空时由 DRM 实例生成一次
+	MUID string `json:"muid,omitempty"`
+}
+
+// DefaultClientIdentity 返回内置的客户端标识
+func DefaultClientIdentity() ClientIdentity {
+	return ClientIdentity{
+		ChromiumVersion:    ChromiumFullVersion,
+		UserAgent:          BaseHeaders["User-Agent"],
+		Origin:             EdgeOrigin,
+		TrustedClientToken: TrustedClientToken,
+		MUIDStrategy:       MUIDRandom,
+	}
+}
+
+var (
+	chromiumVersionPattern = regexp.MustCompile(`^\d+(\.\d+){0,3}$`)
+	muidPattern            = regexp.MustCompile(`^[0-9A-Fa-f]{32}$`)
+)
+
+// withDefaults 用默认值填充空字段
+func (id ClientIdentity) withDefaults() ClientIdentity {
+	def := DefaultClientIdentity()
+	if id.ChromiumVersion == "" {
+		id.ChromiumVersion = def.ChromiumVersion
+	}
+	if id.UserAgent == "" {
+		id.UserAgent = userAgent(id.majorVersion())
+	}
+	if id.Origin == "" {
+		id.Origin = def.Origin
+	}
+	if id.TrustedClientToken == "" {
+		id.TrustedClientToken = def.TrustedClientToken
+	}
+	if id.MUIDStrategy == "" {
+		id.MUIDStrategy = def.MUIDStrategy
+	}
+	id.MUID = strings.ToUpper(id.MUID)
+	return id
+}
+
+// Validate 校验客户端标识，空字段视为默认值
+func (id ClientIdentity) Validate() error {
+	id = id.withDefaults()
+	if !chromiumVersionPattern.MatchString(id.ChromiumVersion) {
+		return fmt.Errorf("%w: chromium version %q", ErrInvalidIdentity, id.ChromiumVersion)
+	}
+	if strings.ContainsAny(id.UserAgent+id.Origin+id.TrustedClientToken, "\r\n") {
+		return fmt.Errorf("%w: header values must not contain line breaks", ErrInvalidIdentity)
+	}
+	switch id.MUIDStrategy {
+	case MUIDRandom, MUIDPersistent, MUIDNone:
+	default:
+		return fmt.Errorf("%w: muid strategy %q (want random, persistent or none)", ErrInvalidIdentity, id.MUIDStrategy)
+	}
+	if id.MUID != "" && !muidPattern.MatchString(id.MUID) {
+		return fmt.Errorf("%w: muid %q is not 32 hex digits", ErrInvalidIdentity, id.MUID)
+	}
+	return nil
+}
+
+// majorVersion 返回 Chromium 主版本号
+func (id ClientIdentity) majorVersion() string {
+	major, _, _ := strings.Cut(id.ChromiumVersion, ".")
+	return major
+}
+
+// secMSGECVersion 返回 Sec-MS-GEC-Version 参数
+func (id ClientIdentity) secMSGECVersion() string {
+	return "1-" + id.ChromiumVersion
+}
+
+// LoadClientIdentity 从 JSON 文件读取客户端标识，文件中省略的字段使用默认值
+func LoadClientIdentity(path string) (ClientIdentity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ClientIdentity{}, err
+	}
+	var id ClientIdentity
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&id); err != nil {
+		return ClientIdentity{}, fmt.Errorf("%w: %s: %v", ErrInvalidIdentity, path, err)
+	}
+	if err := id.Validate(); err != nil {
+		return ClientIdentity{}, err
+	}
+	return id.withDefaults(), nil
+}
+
+// WithChromiumVersion 返回使用指定 Edge 版本的副本
+// UserAgent 为按原版本生成的默认值时随版本更新，显式指定的 UserAgent 保持不变
+func (id ClientIdentity) WithChromiumVersion(version string) ClientIdentity {
+	if id.UserAgent == userAgent(id.withDefaults().majorVersion()) {
+		id.UserAgent = ""
+	}
+	id.ChromiumVersion = version
+	return id
+}
+
+// identityEnv 环境变量与客户端标识字段的对应关系
+var identityEnv = []struct {
+	name  string
+	field func(*ClientIdentity) *string
+}{
+	{"EDGE_TTS_USER_AGENT", func(id *ClientIdentity) *string { return &id.UserAgent }},
+	{"EDGE_TTS_ORIGIN", func(id *ClientIdentity) *string { return &id.Origin }},
+	{"EDGE_TTS_TRUSTED_CLIENT_TOKEN", func(id *ClientIdentity) *string { return &id.TrustedClientToken }},
+	{"EDGE_TTS_MUID_STRATEGY", func(id *ClientIdentity) *string { return (*string)(&id.MUIDStrategy) }},
+	{"EDGE_TTS_MUID", func(id *ClientIdentity) *string { return &id.MUID }},
+}
+
+// ClientIdentityFromEnv 用环境变量覆盖 base 中的字段：
+// EDGE_TTS_CHROMIUM_VERSION、EDGE_TTS_USER_AGENT、EDGE_TTS_ORIGIN、EDGE_TTS_TRUSTED_CLIENT_TOKEN、
+// EDGE_TTS_MUID_STRATEGY 和 EDGE_TTS_MUID，未设置或为空的环境变量不覆盖
+func ClientIdentityFromEnv(base ClientIdentity) (ClientIdentity, error) {
+	id := base
+	if v := os.Getenv("EDGE_TTS_CHROMIUM_VERSION"); v != "" {
+		id = id.WithChromiumVersion(v)
+	}
+	for _, env := range identityEnv {
+		if v := os.Getenv(env.name); v != "" {
+			*env.field(&id) = v
+		}
+	}
+	if err := id.Validate(); err != nil {
+		return ClientIdentity{}, err
+	}
+	return id.withDefaults(), nil
+}
+
+// ResolveClientIdentity 按默认值、file（为空时跳过）、EDGE_TTS_* 环境变量和 overrides 的顺序确定客户端标识，
+// 后者覆盖前者，overrides 中的空字段不覆盖；只覆盖 ChromiumVersion 时 User-Agent 随之更新
+func ResolveClientIdentity(file string, overrides ClientIdentity) (ClientIdentity, error) {
+	id := DefaultClientIdentity()
+	if file != "" {
+		var err error
+		if id, err = LoadClientIdentity(file); err != nil {
+			return ClientIdentity{}, err
+		}
+	}
+	id, err := ClientIdentityFromEnv(id)
+	if err != nil {
+		return ClientIdentity{}, err
+	}
+	if overrides.ChromiumVersion != "" {
+		id = id.WithChromiumVersion(overrides.ChromiumVersion)
+	}
+	for _, field := range []struct{ dst, src *string }{
+		{&id.UserAgent, &overrides.UserAgent},
+		{&id.Origin, &overrides.Origin},
+		{&id.TrustedClientToken, &overrides.TrustedClientToken},
+		{(*string)(&id.MUIDStrategy), (*string)(&overrides.MUIDStrategy)},
+		{&id.MUID, &overrides.MUID},
+	} {
+		if *field.src != "" {
+			*field.dst = *field.src
+		}
+	}
+	if err := id.Validate(); err != nil {
+		return ClientIdentity{}, err
+	}
+	return id.withDefaults(), nil
+}
+
+// userAgent 返回指定 Edge 主版本号的 User-Agent
+func userAgent(major string) string {
+	return fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s.0.0.0 Safari/537.36 Edg/%s.0.0.0", major, major)
+}
+
+// secCHUA 返回指定 Edge 主版本号的 Sec-CH-UA
+func secCHUA(major string) string {
+	return fmt.Sprintf(`" Not;A Brand";v="99", "Microsoft Edge";v="%s", "Chromium";v="%s"`, major, major)
+}
@@ -0,0 +1,191 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+func TestClientIdentityHandshake(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	drm, err := edgetts.NewDRM(edgetts.ClientIdentity{
+		ChromiumVersion:    "150.0.3700.12",
+		Origin:             "chrome-extension://custom",
+		TrustedClientToken: "0123456789ABCDEF0123456789ABCDEF",
+		MUIDStrategy:       edgetts.MUIDPersistent,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := srv.Client()
+	client.DRM = drm
+
+	// 两次合成使用同一个 DRM 实例，模拟服务按地址中的 token 校验 Sec-MS-GEC
+	for i := 0; i < 2; i++ {
+		if _, _, err := synthesize(t, srv, "hello", edgetts.WithClient(client)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := edgetts.ListVoices(context.Background(), &edgetts.ListVoicesOptions{Client: client}); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := srv.Requests()
+	for _, req := range reqs {
+		if got := req.Query.Get("TrustedClientToken"); got != "0123456789ABCDEF0123456789ABCDEF" {
+			t.Errorf("TrustedClientToken = %q", got)
+		}
+		if got := req.Query.Get("Sec-MS-GEC-Version"); got != "1-150.0.3700.12" {
+			t.Errorf("Sec-MS-GEC-Version = %q", got)
+		}
+		if got := req.Header.Get("User-Agent"); !strings.Contains(got, "Edg/150.0.0.0") {
+			t.Errorf("User-Agent = %q", got)
+		}
+		if got := req.Header.Get("Origin"); got != "chrome-extension://custom" {
+			t.Errorf("Origin = %q", got)
+		}
+	}
+	if a, b := reqs[0].Header.Get("Cookie"), reqs[1].Header.Get("Cookie"); a == "" || a != b {
+		t.Errorf("persistent muid cookies = %q, %q", a, b)
+	}
+}
+
+func TestClientIdentityMUIDNone(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	drm, err := edgetts.NewDRM(edgetts.ClientIdentity{MUIDStrategy: edgetts.MUIDNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := srv.Client()
+	client.DRM = drm
+	if _, _, err := synthesize(t, srv, "hello", edgetts.WithClient(client)); err != nil {
+		t.Fatal(err)
+	}
+	req := srv.Requests()[0]
+	if got := req.Header.Get("Cookie"); got != "" {
+		t.Errorf("Cookie = %q, want none", got)
+	}
+	if got := req.Query.Get("Sec-MS-GEC-Version"); got != edgetts.SecMSGECVersion {
+		t.Errorf("Sec-MS-GEC-Version = %q, want default", got)
+	}
+}
+
+func TestDRMClockSkewPerInstance(t *testing.T) {
+	resetClockSkew(t)
+	srv := edgettstest.NewServer(edgettstest.WithClockSkew(2 * time.Hour))
+	defer srv.Close()
+
+	drm, err := edgetts.NewDRM(edgetts.ClientIdentity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := srv.Client()
+	client.DRM = drm
+	if _, _, err := synthesize(t, srv, "hello", edgetts.WithClient(client), edgetts.WithRetry(fastRetry)); err != nil {
+		t.Fatal(err)
+	}
+
+	now := float64(time.Now().Unix())
+	if skew := drm.GetUnixTimestamp() - now; math.Abs(skew-7200) > 5 {
+		t.Errorf("instance skew = %.0fs, want 7200s", skew)
+	}
+	if skew := edgetts.GetDRM().GetUnixTimestamp() - now; math.Abs(skew) > 5 {
+		t.Errorf("global skew = %.0fs, want 0", skew)
+	}
+}
+
+func TestLoadClientIdentity(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	id, err := edgetts.LoadClientIdentity(write("ok.json", `{"chromium_version": "151.0.1.2", "muid_strategy": "persistent", "muid": "0123456789abcdef0123456789abcdef"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.ChromiumVersion != "151.0.1.2" || !strings.Contains(id.UserAgent, "Chrome/151.0.0.0") ||
+		id.Origin != edgetts.EdgeOrigin || id.TrustedClientToken != edgetts.TrustedClientToken ||
+		id.MUID != "0123456789ABCDEF0123456789ABCDEF" {
+		t.Errorf("identity = %+v", id)
+	}
+
+	for name, content := range map[string]string{
+		"unknown.json":  `{"version": "151"}`,
+		"version.json":  `{"chromium_version": "latest"}`,
+		"strategy.json": `{"muid_strategy": "sticky"}`,
+		"muid.json":     `{"muid": "xyz"}`,
+	} {
+		if _, err := edgetts.LoadClientIdentity(write(name, content)); !errors.Is(err, edgetts.ErrInvalidIdentity) {
+			t.Errorf("%s: err = %v, want ErrInvalidIdentity", name, err)
+		}
+	}
+}
+
+func TestClientIdentityFromEnv(t *testing.T) {
+	t.Setenv("EDGE_TTS_CHROMIUM_VERSION", "152.0.1.0")
+	t.Setenv("EDGE_TTS_ORIGIN", "https://relay.example")
+
+	// User-Agent 随版本更新
+	id, err := edgetts.ClientIdentityFromEnv(edgetts.DefaultClientIdentity())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.ChromiumVersion != "152.0.1.0" || !strings.Contains(id.UserAgent, "Edg/152.0.0.0") || id.Origin != "https://relay.example" {
+		t.Errorf("identity = %+v", id)
+	}
+
+	// 显式指定的 User-Agent 保持不变
+	id, err = edgetts.ClientIdentityFromEnv(edgetts.ClientIdentity{UserAgent: "custom-agent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.UserAgent != "custom-agent" {
+		t.Errorf("User-Agent = %q, want custom-agent", id.UserAgent)
+	}
+
+	t.Setenv("EDGE_TTS_MUID_STRATEGY", "sticky")
+	if _, err := edgetts.ClientIdentityFromEnv(edgetts.ClientIdentity{}); !errors.Is(err, edgetts.ErrInvalidIdentity) {
+		t.Errorf("err = %v, want ErrInvalidIdentity", err)
+	}
+}
+
+func TestResolveClientIdentity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identity.json")
+	if err := os.WriteFile(path, []byte(`{"chromium_version": "150.0.1.0", "origin": "https://file.example", "muid_strategy": "persistent"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDGE_TTS_ORIGIN", "https://env.example")
+	t.Setenv("EDGE_TTS_MUID_STRATEGY", "none")
+
+	// 默认值 < 文件 < 环境变量 < overrides
+	id, err := edgetts.ResolveClientIdentity(path, edgetts.ClientIdentity{ChromiumVersion: "151.0.2.0", MUIDStrategy: edgetts.MUIDRandom})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.ChromiumVersion != "151.0.2.0" || !strings.Contains(id.UserAgent, "Edg/151.0.0.0") ||
+		id.Origin != "https://env.example" || id.MUIDStrategy != edgetts.MUIDRandom ||
+		id.TrustedClientToken != edgetts.TrustedClientToken {
+		t.Errorf("identity = %+v", id)
+	}
+
+	if _, err := edgetts.ResolveClientIdentity("", edgetts.ClientIdentity{MUIDStrategy: "sticky"}); !errors.Is(err, edgetts.ErrInvalidIdentity) {
+		t.Errorf("err = %v, want ErrInvalidIdentity", err)
+	}
+}
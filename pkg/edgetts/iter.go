@@ -0,0 +1,37 @@
+//go:build go1.23
+
+package edgetts
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// errStopIteration 调用方提前结束遍历
+var errStopIteration = errors.New("iteration stopped")
+
+// All 返回按顺序产生数据块的迭代器，出错时最后产生一次 (TTSChunk{}, err)
+// 提前结束遍历会取消合成；与 Stream 一样，每个 Communicate 只能遍历一次
+//
+//	for chunk, err := range comm.All(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (c *Communicate) All(ctx context.Context) iter.Seq2[TTSChunk, error] {
+	return func(yield func(TTSChunk, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		err := c.consume(ctx, func(chunk TTSChunk) error {
+			if !yield(chunk, nil) {
+				return errStopIteration
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopIteration) {
+			yield(TTSChunk{}, err)
+		}
+	}
+}
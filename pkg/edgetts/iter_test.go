@@ -0,0 +1,64 @@
+//go:build go1.23
+
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+func TestAll(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	comm, err := edgetts.NewCommunicate("one two three", "", edgetts.WithClient(srv.Client()), edgetts.WithBoundary("WordBoundary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var audio []byte
+	var words []string
+	for chunk, err := range comm.All(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk.Type == "audio" {
+			audio = append(audio, chunk.Data...)
+		} else {
+			words = append(words, chunk.Text)
+		}
+	}
+	if string(audio) != "onetwothree" || len(words) != 3 {
+		t.Errorf("audio = %q, words = %q", audio, words)
+	}
+}
+
+func TestAllBreakAndError(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	comm, err := edgetts.NewCommunicate("one two three", "", edgetts.WithClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range comm.All(context.Background()) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("iterations = %d", n)
+	}
+
+	// 再次遍历返回 ErrStreamAlreadyCalled
+	var last error
+	for _, err := range comm.All(context.Background()) {
+		last = err
+	}
+	if !errors.Is(last, edgetts.ErrStreamAlreadyCalled) {
+		t.Errorf("err = %v, want ErrStreamAlreadyCalled", last)
+	}
+}
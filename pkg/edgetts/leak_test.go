@@ -0,0 +1,180 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+// longText 产生远多于 Stream 缓冲区容量的数据块
+var longText = strings.Repeat("word ", 400)
+
+// waitFor 等待 cond 成立，超时则报错
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// checkNoLeak 记录当前 goroutine 数，返回的函数检查连接已关闭且 goroutine 数回到原值
+func checkNoLeak(t *testing.T, srv *edgettstest.Server) func() {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	return func() {
+		t.Helper()
+		waitFor(t, "connections to close", func() bool { return srv.ActiveConnections() == 0 })
+		waitFor(t, "goroutines to exit", func() bool { return runtime.NumGoroutine() <= before })
+	}
+}
+
+func TestStreamConsumerStops(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		srv := edgettstest.NewServer()
+		check := checkNoLeak(t, srv)
+
+		comm, err := edgetts.NewCommunicate(longText, "",
+			edgetts.WithClient(srv.Client()),
+			edgetts.WithChunkSize(500),
+			edgetts.WithConcurrency(concurrency))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 只读一个数据块就停止读取，然后取消
+		ctx, cancel := context.WithCancel(context.Background())
+		chunkCh, errCh := comm.Stream(ctx)
+		<-chunkCh
+		waitFor(t, "producer to block", func() bool { return len(chunkCh) == cap(chunkCh) })
+		cancel()
+
+		for range chunkCh {
+		}
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("concurrency %d: err = %v, want context.Canceled", concurrency, err)
+		}
+		check()
+		srv.Close()
+	}
+}
+
+func TestCloseStopsStream(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFrameDelay(time.Millisecond))
+	defer srv.Close()
+	check := checkNoLeak(t, srv)
+
+	comm, err := edgetts.NewCommunicate(longText, "", edgetts.WithClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunkCh, _ := comm.Stream(context.Background())
+	<-chunkCh
+
+	// 不再读取，Close 仍然能够返回
+	closed := make(chan struct{})
+	go func() {
+		comm.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked")
+	}
+	check()
+
+	if err := comm.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+	if _, err := comm.StreamSync(context.Background()); !errors.Is(err, edgetts.ErrClosed) {
+		t.Errorf("err = %v, want ErrClosed", err)
+	}
+
+	unused, _ := edgetts.NewCommunicate("hi", "", edgetts.WithClient(srv.Client()))
+	unused.Close()
+	if _, err := unused.StreamSync(context.Background()); !errors.Is(err, edgetts.ErrClosed) {
+		t.Errorf("err = %v, want ErrClosed", err)
+	}
+}
+
+// failingWriter 写入若干字节后返回错误
+type failingWriter struct {
+	n int
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n <= 0 {
+		return 0, errWriteFailed
+	}
+	w.n--
+	return len(p), nil
+}
+
+func TestWriterErrorClosesConnection(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+	check := checkNoLeak(t, srv)
+
+	comm, err := edgetts.NewCommunicate(longText, "", edgetts.WithClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := comm.StreamToWriter(context.Background(), &failingWriter{n: 3}, nil); !errors.Is(err, errWriteFailed) {
+		t.Fatalf("err = %v, want errWriteFailed", err)
+	}
+	check()
+}
+
+func TestAudioReaderCloseEarly(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+	check := checkNoLeak(t, srv)
+
+	comm, err := edgetts.NewCommunicate(longText, "", edgetts.WithClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := comm.AudioReader(context.Background())
+	if _, err := io.ReadFull(r, make([]byte, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
+
+func TestStalledServerCancel(t *testing.T) {
+	// 服务端迟迟不发送数据时取消 ctx，阻塞的读取应立即返回
+	srv := edgettstest.NewServer(edgettstest.WithFrameDelay(time.Second))
+	defer srv.Close()
+	check := checkNoLeak(t, srv)
+
+	comm, err := edgetts.NewCommunicate("hello", "", edgetts.WithClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := comm.StreamSync(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 800*time.Millisecond {
+		t.Errorf("cancel took %v", elapsed)
+	}
+	check()
+}
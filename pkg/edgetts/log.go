@@ -0,0 +1,57 @@
+package edgetts
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// WithLogger 设置日志记录器，默认不输出日志
+// Debug 级别记录协议事件（不含鉴权参数的地址、请求 ID、消息 Path 和字节数），
+// Warn 级别记录重试、重连、去掉说话风格和丢弃的消息等异常情况
+func WithLogger(logger *slog.Logger) CommunicateOption {
+	return func(c *Communicate) {
+		c.logger = logger
+	}
+}
+
+// discardHandler 丢弃所有日志
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discardLogger = slog.New(discardHandler{})
+
+// loggerOrDiscard 返回非 nil 的日志记录器
+func loggerOrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return discardLogger
+	}
+	return logger
+}
+
+// secretParams 不能写入日志的查询参数
+var secretParams = []string{"trustedclienttoken", "sec-ms-gec"}
+
+// redactURL 隐藏地址中的鉴权参数
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid url>"
+	}
+	u.User = nil
+	q := u.Query()
+	for key := range q {
+		for _, secret := range secretParams {
+			if strings.EqualFold(key, secret) {
+				q.Set(key, "REDACTED")
+			}
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
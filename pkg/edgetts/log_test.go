@@ -0,0 +1,80 @@
+package edgetts_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+// syncBuffer 可以被并发写入的缓冲区
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLogger(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithForbiddenHandshakes(1))
+	defer srv.Close()
+
+	var out syncBuffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, _, err := synthesize(t, srv, "hello", edgetts.WithRetry(fastRetry), edgetts.WithLogger(logger)); err != nil {
+		t.Fatal(err)
+	}
+
+	logs := out.String()
+	req := srv.Requests()[0]
+	for _, want := range []string{
+		"level=WARN msg=\"retrying websocket handshake\" attempt=2",
+		"msg=\"websocket connected\" connection_id=" + req.ConnectionID,
+		"msg=\"sent ssml\" connection_id=" + req.ConnectionID + " request_id=" + req.RequestID,
+		"path=turn.end",
+		"Sec-MS-GEC=REDACTED",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("logs missing %q:\n%s", want, logs)
+		}
+	}
+
+	// 只有鉴权参数被隐藏
+	for _, line := range strings.Split(logs, "\n") {
+		if strings.Contains(line, "dialing websocket") && !strings.Contains(line, "Sec-MS-GEC-Version=") {
+			t.Errorf("version parameter redacted: %s", line)
+		}
+	}
+}
+
+func TestLoggerDefaultSilent(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	// 未设置 WithLogger 时不使用 slog 默认记录器
+	var out syncBuffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(prev)
+
+	if _, _, err := synthesize(t, srv, "quiet"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "" {
+		t.Errorf("unexpected logs: %s", out.String())
+	}
+}
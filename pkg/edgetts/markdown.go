@@ -0,0 +1,346 @@
+package edgetts
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	mdHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+|$)`)
+	mdClosingATX = regexp.MustCompile(`[ \t]+#+[ \t]*$|^#+[ \t]*$`)
+	mdSetext     = regexp.MustCompile(`^ {0,3}(?:=+|-+)[ \t]*$`)
+	mdFence      = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	mdRule       = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdQuote      = regexp.MustCompile(`^ {0,3}>[ \t]?`)
+	mdListItem   = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d{1,9}[.)])(?:[ \t]+\[[ xX]\])?[ \t]+`)
+	mdLinkDef    = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:[ \t]*\S+`)
+	mdTableDelim = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)+\|?[ \t]*$`)
+	// mdInlineTag 内联 HTML 标签、注释和 <URL> 形式的自动链接
+	mdInlineTag = regexp.MustCompile(`^<(?:/?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?|!--[^>]*--|[A-Za-z][A-Za-z0-9+.-]*:[^\s<>]*|[^\s@<>]+@[^\s@<>]+)>`)
+	// mdRawText <script> 和 <style> 开始标签，元素内容与 HTML 输入一样被丢弃
+	mdRawText = regexp.MustCompile(`(?i)^ {0,3}<(script|style)(?:[\s/>]|$)`)
+)
+
+// mdLine 源文档中的一行，[start, end) 不含换行符，next 是下一行的开头
+type mdLine struct {
+	start, end, next int
+}
+
+// splitMarkdownLines 按行切分源文档
+func splitMarkdownLines(src string) []mdLine {
+	var lines []mdLine
+	for pos := 0; pos < len(src); {
+		end := strings.IndexByte(src[pos:], '\n')
+		next := len(src)
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += pos
+			next = end + 1
+		}
+		if end > pos && src[end-1] == '\r' {
+			end--
+		}
+		lines = append(lines, mdLine{pos, end, next})
+		pos = next
+	}
+	return lines
+}
+
+// parseMarkdown 把 Markdown 文档转换为朗读文本
+// 支持 ATX 和 Setext 标题、列表、引用、围栏代码块、表格、链接、图片和内联标记，
+// 链接只朗读文本，图片朗读替代文本，HTML 标签被去掉
+func parseMarkdown(src string, opts DocumentOptions) []docSegment {
+	b := &docBuilder{opts: opts}
+	lines := splitMarkdownLines(src)
+
+	for i := 0; i < len(lines); i++ {
+		ln := lines[i]
+		line := src[ln.start:ln.end]
+
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			// 围栏代码块到相同字符、不短于开头的围栏为止
+			fence := m[1]
+			bodyStart, bodyEnd, end := ln.next, len(src), len(src)
+			j := i + 1
+			for ; j < len(lines); j++ {
+				if strings.HasPrefix(strings.TrimLeft(src[lines[j].start:lines[j].end], " "), fence) {
+					bodyEnd, end = lines[j].start, lines[j].end
+					break
+				}
+			}
+			b.codeBlock(src, ln.start, end, bodyStart, bodyEnd)
+			i = j
+			continue
+		}
+
+		if m := mdRawText.FindStringSubmatch(line); m != nil {
+			// HTML 块到包含结束标签的行为止，没有结束标签时到文档末尾
+			j := len(lines)
+			if end := rawTextEnd(src[ln.start:], m[1]); end >= 0 {
+				for j = i; lines[j].end < ln.start+end; j++ {
+				}
+			}
+			i = j
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			b.pause(opts.ParagraphPause)
+			continue
+		case mdRule.MatchString(line) || mdTableDelim.MatchString(line) || mdLinkDef.MatchString(line):
+			b.pause(opts.ParagraphPause)
+			continue
+		}
+
+		// 引用和列表标记可以嵌套，依次去掉
+		off := ln.start
+		item := false
+		for {
+			if loc := mdQuote.FindStringIndex(src[off:ln.end]); loc != nil {
+				off += loc[1]
+				continue
+			}
+			if loc := mdListItem.FindStringIndex(src[off:ln.end]); loc != nil && !mdRule.MatchString(src[off:ln.end]) {
+				off += loc[1]
+				item = true
+				continue
+			}
+			break
+		}
+		if item {
+			b.pause(opts.ItemPause)
+		}
+		content := src[off:ln.end]
+
+		switch {
+		case mdHeading.MatchString(content):
+			loc := mdHeading.FindStringIndex(content)
+			text := content[loc[1]:]
+			if c := mdClosingATX.FindStringIndex(text); c != nil {
+				text = text[:c[0]]
+			}
+			b.heading(text, off+loc[1])
+		case !item && off == ln.start && i+1 < len(lines) && mdSetext.MatchString(src[lines[i+1].start:lines[i+1].end]):
+			b.heading(content, off)
+			i++
+		case strings.HasPrefix(strings.TrimSpace(content), "|"):
+			b.tableRow(content, off)
+		default:
+			b.markdownInline(content, off)
+			b.text(src[ln.end:ln.next], ln.end)
+		}
+	}
+	return b.segs
+}
+
+// heading 朗读标题，前后停顿
+func (b *docBuilder) heading(text string, start int) {
+	b.pause(b.opts.ParagraphPause)
+	b.emphasis = b.opts.EmphasizeHeadings
+	b.markdownInline(text, start)
+	b.emphasis = false
+	b.pause(b.opts.HeadingPause)
+}
+
+// codeBlock 按 CodeBlocks 设置处理源文档 [start, end) 的代码块，[bodyStart, bodyEnd) 是代码内容
+func (b *docBuilder) codeBlock(src string, start, end, bodyStart, bodyEnd int) {
+	b.pause(b.opts.ParagraphPause)
+	switch b.opts.CodeBlocks {
+	case CodeAnnounce:
+		b.spoken(b.opts.CodeAnnouncement, start, end)
+	case CodeRead:
+		if bodyStart < bodyEnd {
+			b.text(src[bodyStart:bodyEnd], bodyStart)
+		}
+	}
+	b.pause(b.opts.ParagraphPause)
+}
+
+// tableRow 朗读表格的一行，单元格之间停顿
+func (b *docBuilder) tableRow(row string, start int) {
+	b.pause(b.opts.ItemPause)
+	cell := 0
+	for i := 0; i <= len(row); i++ {
+		if i < len(row) && (row[i] != '|' || (i > 0 && row[i-1] == '\\')) {
+			continue
+		}
+		if text := row[cell:i]; strings.TrimSpace(text) != "" {
+			b.markdownInline(text, start+cell)
+			b.pause(b.opts.ItemPause)
+		}
+		cell = i + 1
+	}
+}
+
+// markdownInline 朗读一行内的文本，去掉强调、代码、链接等内联标记，start 是 s 在源文档中的位置
+func (b *docBuilder) markdownInline(s string, start int) {
+	run := 0 // 尚未添加的原文从 run 开始
+	flush := func(end int) {
+		if end > run {
+			// Markdown 中可以使用 HTML 字符引用
+			b.htmlText(s[run:end], start+run)
+		}
+	}
+	skip := func(from, to int) {
+		flush(from)
+		run = to
+	}
+
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			// 转义的标点按原样朗读
+			skip(i, i+1)
+			i += 2
+
+		case c == '`':
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], "`"))
+			closing := strings.Index(s[i+n:], s[i:i+n])
+			if closing < 0 {
+				i += n
+				continue
+			}
+			skip(i, i+n)
+			flush(i + n + closing)
+			i += n + closing + n
+			run = i
+
+		case c == '[' || c == '!' && strings.HasPrefix(s[i:], "!["):
+			open := i
+			if c == '!' {
+				open++
+			}
+			textEnd, end, ok := parseMarkdownLink(s, open)
+			if !ok {
+				i = open + 1
+				continue
+			}
+			// 链接只朗读文本，图片朗读替代文本
+			flush(i)
+			b.markdownInline(s[open+1:textEnd], start+open+1)
+			i = end
+			run = i
+
+		case c == '<':
+			if m := mdRawText.FindStringSubmatch(s[i:]); m != nil {
+				// 丢弃 <script>、<style> 元素的内容，没有结束标签时丢弃到行尾
+				end := rawTextEnd(s[i:], m[1])
+				if end < 0 {
+					end = len(s) - i
+				}
+				skip(i, i+end)
+				i += end
+				continue
+			}
+			if loc := mdInlineTag.FindStringIndex(s[i:]); loc != nil {
+				skip(i, i+loc[1])
+				i += loc[1]
+				continue
+			}
+			i++
+
+		case c == '*' || c == '_' || c == '~':
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], string(c)))
+			if isEmphasisDelimiter(s, i, n) {
+				skip(i, i+n)
+			}
+			i += n
+
+		default:
+			i++
+		}
+	}
+	flush(len(s))
+}
+
+// rawTextEnd 返回 s 中 name 元素结束标签之后的位置，不区分大小写，找不到时返回 -1
+func rawTextEnd(s, name string) int {
+	// 逐字节比较标签，避免大小写转换改变字节长度导致位置错位
+	tag := "</" + name
+	closing := -1
+	for i := strings.Index(s, "</"); i >= 0 && i+len(tag) <= len(s); {
+		if strings.EqualFold(s[i:i+len(tag)], tag) {
+			closing = i
+			break
+		}
+		next := strings.Index(s[i+2:], "</")
+		if next < 0 {
+			break
+		}
+		i += 2 + next
+	}
+	if closing < 0 {
+		return -1
+	}
+	gt := strings.IndexByte(s[closing:], '>')
+	if gt < 0 {
+		return -1
+	}
+	return closing + gt + 1
+}
+
+// isEmphasisDelimiter 判断 s[i:i+n] 的 *、_ 或 ~ 是否是强调或删除线标记
+// 两侧都是空白的（如 2 * 3）、单词内部的下划线（如 snake_case）和单个 ~ 按原文朗读
+func isEmphasisDelimiter(s string, i, n int) bool {
+	prev, _ := utf8.DecodeLastRuneInString(s[:i])
+	next, _ := utf8.DecodeRuneInString(s[i+n:])
+	prevSpace := i == 0 || unicode.IsSpace(prev)
+	nextSpace := i+n == len(s) || unicode.IsSpace(next)
+	switch {
+	case prevSpace && nextSpace:
+		return false
+	case s[i] == '_':
+		return !(i > 0 && isWordRune(prev) && i+n < len(s) && isWordRune(next))
+	case s[i] == '~':
+		return n == 2
+	}
+	return true
+}
+
+// isASCIIPunct 判断是否是可以用反斜杠转义的 ASCII 标点
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && (unicode.IsPunct(rune(c)) || unicode.IsSymbol(rune(c)))
+}
+
+// parseMarkdownLink 解析 s[open] 处的 [text](url) 或 [text][ref]
+// 返回 text 的结束位置（右方括号）和整个链接的结束位置
+func parseMarkdownLink(s string, open int) (textEnd, end int, ok bool) {
+	textEnd = matchBracket(s, open, '[', ']')
+	if textEnd < 0 || textEnd+1 >= len(s) {
+		return 0, 0, false
+	}
+	switch s[textEnd+1] {
+	case '(':
+		if end = matchBracket(s, textEnd+1, '(', ')'); end >= 0 {
+			return textEnd, end + 1, true
+		}
+	case '[':
+		if end = matchBracket(s, textEnd+1, '[', ']'); end >= 0 {
+			return textEnd, end + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// matchBracket 返回与 s[open] 匹配的右括号位置，跳过转义字符，找不到时返回 -1
+func matchBracket(s string, open int, left, right byte) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case left:
+			depth++
+		case right:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,104 @@
+package edgetts
+
+import (
+	"context"
+	"sync"
+)
+
+// WithConcurrency 设置并行合成使用的连接数，n <= 1 时在一个连接上顺序合成
+// 并行模式下各文本块的音频和边界仍按原文顺序输出，偏移量连续
+func WithConcurrency(n int) CommunicateOption {
+	return func(c *Communicate) {
+		c.concurrency = n
+	}
+}
+
+// turnResult 一个文本块的合成结果
+type turnResult struct {
+	chunks []TTSChunk
+	err    error
+}
+
+// streamParallel 用多个连接并行合成文本块，按原文顺序重组输出
+func (c *Communicate) streamParallel(ctx context.Context, emit func(TTSChunk) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	workers := min(c.concurrency, len(c.texts))
+	results := make([]chan turnResult, len(c.texts))
+	for i := range results {
+		results[i] = make(chan turnResult, 1)
+	}
+
+	// window 限制已合成但尚未输出的文本块数量，避免慢速消费时占用过多内存
+	window := make(chan struct{}, 2*workers)
+	jobs := make(chan int)
+
+	go func() {
+		defer close(jobs)
+		for i := range c.texts {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	// 返回前取消尚未完成的回合，并等待所有连接关闭
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var conn *ttsConn
+			defer func() {
+				if conn != nil {
+					conn.Close()
+				}
+			}()
+
+			for i := range jobs {
+				var chunks []TTSChunk
+				err := c.synthTurn(ctx, &conn, i, func(chunk TTSChunk) error {
+					chunks = append(chunks, chunk)
+					return nil
+				}, func() {
+					chunks = nil
+				})
+				results[i] <- turnResult{chunks: chunks, err: err}
+			}
+		}()
+	}
+
+	for i, text := range c.texts {
+		var res turnResult
+		select {
+		case res = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if res.err != nil {
+			return res.err
+		}
+
+		c.state.PartialText = text
+		for _, chunk := range res.chunks {
+			if err := emit(c.compensate(i, chunk)); err != nil {
+				return err
+			}
+		}
+		c.endTurn()
+		<-window
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package edgetts
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// parseProxyURL 解析代理地址，支持 http 和 socks5（含用户名密码）
+// 未指定 scheme 时按 http 处理，空字符串返回 nil
+func parseProxyURL(proxy string) (*url.URL, error) {
+	proxy = strings.TrimSpace(proxy)
+	if proxy == "" {
+		return nil, nil
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProxy, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: missing host in %q", ErrInvalidProxy, proxy)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		u.Scheme = "http"
+	case "socks5", "socks5h":
+		// 两者都由代理端解析目标域名
+		u.Scheme = "socks5"
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrInvalidProxy, u.Scheme)
+	}
+	return u, nil
+}
+
+// proxyFunc 返回代理选择函数
+// 显式指定代理时总是使用该代理，否则读取 HTTP_PROXY/HTTPS_PROXY/NO_PROXY 环境变量
+func proxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	proxyURL, err := parseProxyURL(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL != nil {
+		return http.ProxyURL(proxyURL), nil
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		// 每次读取环境变量，而不是像 http.ProxyFromEnvironment 那样只读一次
+		u, err := httpproxy.FromEnvironment().ProxyFunc()(req.URL)
+		if err != nil || u == nil {
+			return u, err
+		}
+		return parseProxyURL(u.String())
+	}, nil
+}
+
+// newWebSocketDialer 创建带代理设置的 WebSocket dialer
+func newWebSocketDialer(proxy string, handshakeTimeout time.Duration) (*websocket.Dialer, error) {
+	pf, err := proxyFunc(proxy)
+	if err != nil {
+		return nil, err
+	}
+	return &websocket.Dialer{
+		Proxy:            pf,
+		HandshakeTimeout: handshakeTimeout,
+	}, nil
+}
+
+// newHTTPTransport 创建带代理设置的 HTTP transport
+func newHTTPTransport(proxy string) (*http.Transport, error) {
+	pf, err := proxyFunc(proxy)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = pf
+	return transport, nil
+}
@@ -0,0 +1,418 @@
+package edgetts
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// targetServer 同时提供 WebSocket 回显和普通 HTTP 响应
+func targetServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			mt, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(mt, data)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// proxyRecorder 记录代理收到的请求
+type proxyRecorder struct {
+	mu       sync.Mutex
+	requests []string
+	auth     []string
+}
+
+func (p *proxyRecorder) record(req, auth string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	p.auth = append(p.auth, auth)
+}
+
+func (p *proxyRecorder) snapshot() ([]string, []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.requests...), append([]string(nil), p.auth...)
+}
+
+// newHTTPProxy 启动一个支持 CONNECT 和普通转发的 HTTP 代理替身
+func newHTTPProxy(t *testing.T, user, pass string) (*httptest.Server, *proxyRecorder) {
+	t.Helper()
+	rec := &proxyRecorder{}
+	wantAuth := ""
+	if user != "" {
+		wantAuth = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Proxy-Authorization")
+		rec.record(r.Method+" "+r.Host, auth)
+		if auth != wantAuth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		if r.Method != http.MethodConnect {
+			r.RequestURI = ""
+			r.Header.Del("Proxy-Authorization")
+			resp, err := http.DefaultTransport.RoundTrip(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			defer resp.Body.Close()
+			for k, v := range resp.Header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(resp.StatusCode)
+			io.Copy(w, resp.Body)
+			return
+		}
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		client, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		pipe(client, upstream)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, rec
+}
+
+// newSOCKS5Proxy 启动一个支持用户名密码认证的 SOCKS5 代理替身
+func newSOCKS5Proxy(t *testing.T, user, pass string) (net.Listener, *proxyRecorder) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	rec := &proxyRecorder{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSOCKS5(conn, user, pass, rec)
+		}
+	}()
+	return ln, rec
+}
+
+func serveSOCKS5(conn net.Conn, user, pass string, rec *proxyRecorder) {
+	br := bufio.NewReader(conn)
+	fail := func() { conn.Close() }
+
+	// 问候：VER NMETHODS METHODS...
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(br, head); err != nil || head[0] != 5 {
+		fail()
+		return
+	}
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(br, methods); err != nil {
+		fail()
+		return
+	}
+	if user == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+		// 用户名密码子协商：VER ULEN UNAME PLEN PASSWD
+		ver, _ := br.ReadByte()
+		ulen, _ := br.ReadByte()
+		uname := make([]byte, ulen)
+		io.ReadFull(br, uname)
+		plen, _ := br.ReadByte()
+		passwd := make([]byte, plen)
+		io.ReadFull(br, passwd)
+		if ver != 1 || string(uname) != user || string(passwd) != pass {
+			rec.record("AUTH", string(uname)+":"+string(passwd))
+			conn.Write([]byte{1, 1})
+			fail()
+			return
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	// 请求：VER CMD RSV ATYP DST.ADDR DST.PORT
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(br, req); err != nil || req[1] != 1 {
+		fail()
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(br, ip)
+		host = net.IP(ip).String()
+	case 3:
+		n, _ := br.ReadByte()
+		name := make([]byte, n)
+		io.ReadFull(br, name)
+		host = string(name)
+	case 4:
+		ip := make([]byte, 16)
+		io.ReadFull(br, ip)
+		host = net.IP(ip).String()
+	default:
+		fail()
+		return
+	}
+	portBytes := make([]byte, 2)
+	io.ReadFull(br, portBytes)
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(portBytes))))
+	rec.record("CONNECT "+addr, user)
+
+	upstream, err := net.Dial("tcp", addr)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		fail()
+		return
+	}
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	pipe(&bufferedConn{Conn: conn, r: br}, upstream)
+}
+
+// bufferedConn 保留已缓冲的读取数据
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+func pipe(a, b net.Conn) {
+	var once sync.Once
+	closeBoth := func() {
+		a.Close()
+		b.Close()
+	}
+	go func() {
+		io.Copy(a, b)
+		once.Do(closeBoth)
+	}()
+	io.Copy(b, a)
+	once.Do(closeBoth)
+}
+
+func wsURL(srv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func echoThrough(t *testing.T, proxy, target string) {
+	t.Helper()
+	dialer, err := newWebSocketDialer(proxy, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _, err := dialer.Dial(target, nil)
+	if err != nil {
+		t.Fatalf("dial through proxy: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "ping" {
+		t.Fatalf("echo = %q, want %q", data, "ping")
+	}
+}
+
+func getThrough(t *testing.T, proxy, target string) {
+	t.Helper()
+	transport, err := newHTTPTransport(proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatalf("get through proxy: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Fatalf("got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestParseProxyURL(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"127.0.0.1:8080", "http://127.0.0.1:8080", false},
+		{"http://u:p@proxy:3128", "http://u:p@proxy:3128", false},
+		{"socks5://proxy:1080", "socks5://proxy:1080", false},
+		{"socks5h://proxy:1080", "socks5://proxy:1080", false},
+		{"ftp://proxy:21", "", true},
+		{"http://", "", true},
+	}
+	for _, tt := range tests {
+		u, err := parseProxyURL(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidProxy) {
+				t.Errorf("parseProxyURL(%q) err = %v, want ErrInvalidProxy", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseProxyURL(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		got := ""
+		if u != nil {
+			got = u.String()
+		}
+		if got != tt.want {
+			t.Errorf("parseProxyURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+	t.Setenv("HTTP_PROXY", "socks5://env-socks:1080")
+	t.Setenv("NO_PROXY", "internal.example")
+
+	pf, err := proxyFunc("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(rawURL, want string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", rawURL, nil)
+		u, err := pf(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if u != nil {
+			got = u.String()
+		}
+		if got != want {
+			t.Errorf("proxy for %s = %q, want %q", rawURL, got, want)
+		}
+	}
+
+	check("https://speech.platform.bing.com/", "http://env-proxy:3128")
+	check("http://speech.platform.bing.com/", "socks5://env-socks:1080")
+	check("https://internal.example/", "")
+
+	// 显式代理优先于环境变量
+	pf, err = proxyFunc("socks5://explicit:1080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "https://speech.platform.bing.com/", nil)
+	if u, _ := pf(req); u == nil || u.Host != "explicit:1080" {
+		t.Errorf("explicit proxy = %v, want explicit:1080", u)
+	}
+}
+
+func TestHTTPConnectProxy(t *testing.T) {
+	target := targetServer(t)
+	proxy, rec := newHTTPProxy(t, "alice", "s3cret")
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://alice:s3cret@", 1)
+
+	echoThrough(t, proxyURL, wsURL(target))
+	getThrough(t, proxyURL, target.URL)
+
+	reqs, auths := rec.snapshot()
+	if len(reqs) != 2 {
+		t.Fatalf("proxy saw %d requests, want 2: %v", len(reqs), reqs)
+	}
+	host := strings.TrimPrefix(target.URL, "http://")
+	if reqs[0] != "CONNECT "+host {
+		t.Errorf("first request = %q, want CONNECT %s", reqs[0], host)
+	}
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:s3cret"))
+	for i, a := range auths {
+		if a != want {
+			t.Errorf("request %d Proxy-Authorization = %q, want %q", i, a, want)
+		}
+	}
+}
+
+func TestHTTPConnectProxyRejectsBadCredentials(t *testing.T) {
+	target := targetServer(t)
+	proxy, _ := newHTTPProxy(t, "alice", "s3cret")
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://alice:wrong@", 1)
+
+	dialer, err := newWebSocketDialer(proxyURL, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn, _, err := dialer.Dial(wsURL(target), nil); err == nil {
+		conn.Close()
+		t.Fatal("dial succeeded with bad proxy credentials")
+	}
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	target := targetServer(t)
+	ln, rec := newSOCKS5Proxy(t, "bob", "hunter2")
+	proxyURL := "socks5://bob:hunter2@" + ln.Addr().String()
+
+	echoThrough(t, proxyURL, wsURL(target))
+	getThrough(t, proxyURL, target.URL)
+
+	reqs, _ := rec.snapshot()
+	host := strings.TrimPrefix(target.URL, "http://")
+	if len(reqs) != 2 || reqs[0] != "CONNECT "+host || reqs[1] != "CONNECT "+host {
+		t.Fatalf("socks5 proxy requests = %v, want two CONNECT %s", reqs, host)
+	}
+}
+
+func TestCommunicateRejectsInvalidProxy(t *testing.T) {
+	_, err := NewCommunicate("hello", "", WithProxy("ftp://proxy:21"))
+	if !errors.Is(err, ErrInvalidProxy) {
+		t.Fatalf("err = %v, want ErrInvalidProxy", err)
+	}
+}
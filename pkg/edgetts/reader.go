@@ -0,0 +1,43 @@
+package edgetts
+
+import (
+	"context"
+	"io"
+)
+
+// AudioReader 返回合成音频的 io.ReadCloser，按 WithOutputFormat 设置的格式输出
+// 合成在后台进行，失败时错误由 Read 返回；提前 Close 会取消合成
+// 与 Stream 一样，每个 Communicate 只能调用一次
+func (c *Communicate) AudioReader(ctx context.Context) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := c.consume(ctx, func(chunk TTSChunk) error {
+			if chunk.Type != "audio" {
+				return nil
+			}
+			_, err := pw.Write(chunk.Data)
+			return err
+		})
+		// err 为 nil 时读取方得到 io.EOF
+		pw.CloseWithError(err)
+	}()
+	return &audioReader{PipeReader: pr, cancel: cancel, done: done}
+}
+
+// audioReader 关闭时取消合成并等待连接关闭
+type audioReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Close 实现 io.Closer
+func (r *audioReader) Close() error {
+	r.cancel()
+	err := r.PipeReader.Close()
+	<-r.done
+	return err
+}
@@ -0,0 +1,45 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+func TestAudioReader(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	comm, err := edgetts.NewCommunicate("read me please", "", edgetts.WithClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := comm.AudioReader(context.Background())
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "readmeplease" {
+		t.Errorf("audio = %q", data)
+	}
+}
+
+func TestAudioReaderError(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFault(edgettstest.FaultNoAudio))
+	defer srv.Close()
+
+	comm, err := edgetts.NewCommunicate("silence", "", edgetts.WithClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := comm.AudioReader(context.Background())
+	defer r.Close()
+	if _, err := io.ReadAll(r); !errors.Is(err, edgetts.ErrNoAudioReceived) {
+		t.Errorf("err = %v, want ErrNoAudioReceived", err)
+	}
+}
@@ -0,0 +1,143 @@
+package edgetts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy 重试策略，用于 WebSocket 握手和语音列表请求
+type RetryPolicy struct {
+	// MaxAttempts 最大尝试次数（含首次），小于等于 1 表示不重试
+	MaxAttempts int
+	// InitialBackoff 首次重试前的等待时间
+	InitialBackoff time.Duration
+	// MaxBackoff 单次等待时间上限
+	MaxBackoff time.Duration
+	// Multiplier 每次重试等待时间的增长倍数
+	Multiplier float64
+	// Jitter 等待时间的随机抖动比例（0~1）
+	Jitter float64
+	// Retryable 判断错误是否可重试，为空时使用 IsRetryable
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// NoRetry 不重试
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// WithRetry 设置握手重试策略
+func WithRetry(policy RetryPolicy) CommunicateOption {
+	return func(c *Communicate) {
+		c.retry = policy
+	}
+}
+
+// retryableError 自行判断能否重试的错误，例如 HandshakeError、ProtocolError 和 ChunkError
+type retryableError interface {
+	Retryable() bool
+}
+
+// IsRetryable 判断错误是否可以重试
+// 403（时钟偏移）、408、429、5xx 以及网络超时、连接中断类错误可以重试，协议错误不可重试
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var re retryableError
+	if errors.As(err, &re) {
+		return re.Retryable()
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryable 判断错误是否可重试
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// backoff 返回第 n 次重试（从 1 开始）前的等待时间
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := float64(p.InitialBackoff)
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	for i := 1; i < n; i++ {
+		d *= mult
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+// do 按策略执行 op，onRetry 不为空时在第 n 次失败后、等待重试前调用
+// 遇到 403 时用响应中的 Date 头校正 DRM 时钟偏移后再重试
+// 等待重试期间 ctx 结束时返回包装了最后一次错误的 ctx.Err()
+func (p RetryPolicy) do(ctx context.Context, drm *DRM, onRetry func(n int, err error, wait time.Duration), op func() error) error {
+	attempts := max(p.MaxAttempts, 1)
+	for n := 1; ; n++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		if n >= attempts || !p.retryable(err) {
+			return err
+		}
+
+		var he *HandshakeError
+		if errors.As(err, &he) && he.StatusCode == http.StatusForbidden && !he.Date.IsZero() {
+			// 没有 Date 头时仍然重试，token 可能刚好跨过 5 分钟窗口
+			drm.syncServerTime(he.Date)
+		}
+
+		wait := p.backoff(n)
+		if onRetry != nil {
+			onRetry(n, err, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last attempt: %w)", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
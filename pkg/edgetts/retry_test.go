@@ -0,0 +1,152 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+var fastRetry = edgetts.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+	Multiplier:     2,
+}
+
+// resetClockSkew 测试结束后把全局 DRM 的时钟偏移恢复为 0
+func resetClockSkew(t *testing.T) {
+	t.Cleanup(func() {
+		drm := edgetts.GetDRM()
+		drm.AdjClockSkewSeconds(float64(time.Now().Unix()) - drm.GetUnixTimestamp())
+	})
+}
+
+func TestStreamRecoversFromClockSkew(t *testing.T) {
+	resetClockSkew(t)
+	srv := edgettstest.NewServer(edgettstest.WithClockSkew(2 * time.Hour))
+	defer srv.Close()
+
+	audio, _, err := synthesize(t, srv, "hello skew", edgetts.WithRetry(fastRetry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(audio) != "helloskew" {
+		t.Errorf("audio = %q", audio)
+	}
+	if srv.Rejected() != 1 || srv.Handshakes() != 2 {
+		t.Errorf("rejected = %d, handshakes = %d; want 1 and 2", srv.Rejected(), srv.Handshakes())
+	}
+}
+
+func TestStreamRetriesForbiddenHandshake(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithForbiddenHandshakes(2))
+	defer srv.Close()
+
+	if _, _, err := synthesize(t, srv, "third time lucky", edgetts.WithRetry(fastRetry)); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Handshakes() != 3 {
+		t.Errorf("handshakes = %d, want 3", srv.Handshakes())
+	}
+}
+
+func TestStreamGivesUpAfterMaxAttempts(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFault(edgettstest.FaultForbidden))
+	defer srv.Close()
+
+	if _, _, err := synthesize(t, srv, "never", edgetts.WithRetry(fastRetry)); err == nil {
+		t.Fatal("expected error")
+	}
+	if srv.Handshakes() != fastRetry.MaxAttempts {
+		t.Errorf("handshakes = %d, want %d", srv.Handshakes(), fastRetry.MaxAttempts)
+	}
+}
+
+func TestStreamDoesNotRetryProtocolErrors(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFault(edgettstest.FaultUnknownPath))
+	defer srv.Close()
+
+	_, _, err := synthesize(t, srv, "once", edgetts.WithRetry(fastRetry))
+	if !errors.Is(err, edgetts.ErrUnknownResponse) {
+		t.Fatalf("err = %v", err)
+	}
+	if srv.Handshakes() != 1 {
+		t.Errorf("handshakes = %d, want 1", srv.Handshakes())
+	}
+}
+
+func TestRetryCanceledDuringBackoff(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFault(edgettstest.FaultForbidden))
+	defer srv.Close()
+
+	slowRetry := fastRetry
+	slowRetry.InitialBackoff = time.Minute
+	slowRetry.MaxBackoff = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := edgetts.ListVoices(ctx, &edgetts.ListVoicesOptions{Client: srv.Client(), Retry: &slowRetry})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	// 保留最后一次尝试的错误
+	var he *edgetts.HandshakeError
+	if !errors.As(err, &he) || he.StatusCode != http.StatusForbidden {
+		t.Errorf("err = %v, want the 403 of the last attempt", err)
+	}
+}
+
+func TestListVoicesRecoversFromClockSkew(t *testing.T) {
+	resetClockSkew(t)
+	srv := edgettstest.NewServer(edgettstest.WithClockSkew(-3 * time.Hour))
+	defer srv.Close()
+
+	voices, err := edgetts.ListVoices(context.Background(), &edgetts.ListVoicesOptions{
+		Client: srv.Client(),
+		Retry:  &fastRetry,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(voices) == 0 {
+		t.Fatal("no voices")
+	}
+	if srv.Rejected() != 1 {
+		t.Errorf("rejected = %d, want 1", srv.Rejected())
+	}
+}
+
+func TestHandleClientResponseError(t *testing.T) {
+	drm, err := edgetts.NewDRM(edgetts.ClientIdentity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverTime := time.Now().Add(90 * time.Minute)
+	resp := &http.Response{Header: http.Header{"Date": {serverTime.UTC().Format(http.TimeFormat)}}}
+	if err := drm.HandleClientResponseError(resp); err != nil {
+		t.Fatal(err)
+	}
+	if skew := drm.GetUnixTimestamp() - float64(time.Now().Unix()); math.Abs(skew-5400) > 5 {
+		t.Errorf("skew = %.0fs, want 5400s", skew)
+	}
+
+	if err := drm.HandleClientResponseError(&http.Response{Header: http.Header{}}); !errors.Is(err, edgetts.ErrSkewAdjustment) {
+		t.Errorf("err = %v, want ErrSkewAdjustment", err)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	if edgetts.IsRetryable(context.Canceled) {
+		t.Error("context.Canceled should not be retryable")
+	}
+	if edgetts.IsRetryable(edgetts.ErrUnexpectedResponse) {
+		t.Error("protocol errors should not be retryable")
+	}
+}
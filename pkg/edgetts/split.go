@@ -0,0 +1,209 @@
+package edgetts
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultChunkSize 每个文本块默认的最大字节数
+const DefaultChunkSize = 4096
+
+// Splitter 把转义后的文本切分为不超过 limit 字节的文本块
+// 实现不能在 XML 实体（如 &amp;）内部切分
+type Splitter interface {
+	Split(escapedText string, limit int) [][]byte
+}
+
+// SplitterFunc 函数形式的 Splitter
+type SplitterFunc func(escapedText string, limit int) [][]byte
+
+// Split 实现 Splitter
+func (f SplitterFunc) Split(escapedText string, limit int) [][]byte {
+	return f(escapedText, limit)
+}
+
+// ByteSplitter 在限制内最后一个换行或空格处切分，即 SplitTextByByteLength
+var ByteSplitter Splitter = SplitterFunc(SplitTextByByteLength)
+
+// SentenceSplitter 按段落、句子、子句、空白的优先级切分
+// 句子结束包括 .!? 以及中文的 。！？ 和省略号
+type SentenceSplitter struct{}
+
+// DefaultSplitter 默认的切分策略
+var DefaultSplitter Splitter = SentenceSplitter{}
+
+// Split 实现 Splitter
+func (SentenceSplitter) Split(escapedText string, limit int) [][]byte {
+	if limit <= 0 {
+		return nil
+	}
+
+	text := []byte(escapedText)
+	var result [][]byte
+	for len(text) > limit {
+		splitAt := findSentenceSplitPoint(text, limit)
+		if chunk := bytes.TrimSpace(text[:splitAt]); len(chunk) > 0 {
+			result = append(result, chunk)
+		}
+		text = text[splitAt:]
+	}
+	if chunk := bytes.TrimSpace(text); len(chunk) > 0 {
+		result = append(result, chunk)
+	}
+	return result
+}
+
+// chunkSpans 依次在 text 中查找各文本块，返回它们的字节范围
+// 自定义 Splitter 返回的文本块不一定是 text 的子串，找不到时范围为 -1
+func chunkSpans(text string, chunks [][]byte) [][2]int {
+	spans := make([][2]int, len(chunks))
+	pos := 0
+	for i, chunk := range chunks {
+		j := strings.Index(text[pos:], string(chunk))
+		if j < 0 {
+			spans[i] = [2]int{-1, -1}
+			continue
+		}
+		spans[i] = [2]int{pos + j, pos + j + len(chunk)}
+		pos = spans[i][1]
+	}
+	return spans
+}
+
+// WithSplitter 设置文本切分策略，默认使用 DefaultSplitter
+func WithSplitter(s Splitter) CommunicateOption {
+	return func(c *Communicate) {
+		c.splitter = s
+	}
+}
+
+// WithChunkSize 设置每个文本块的最大字节数，默认 DefaultChunkSize
+func WithChunkSize(n int) CommunicateOption {
+	return func(c *Communicate) {
+		c.chunkSize = n
+	}
+}
+
+// 切分点的优先级，数值越小越优先
+const (
+	levelParagraph = iota
+	levelSentence
+	levelClause
+	levelSpace
+	numLevels
+)
+
+// findSentenceSplitPoint 在 text[:limit] 内查找切分点，返回值在 1 和 limit 之间
+// 优先选择不短于 limit/3 的高优先级切分点，避免产生过短的文本块
+func findSentenceSplitPoint(text []byte, limit int) int {
+	if len(text) <= limit {
+		return len(text)
+	}
+
+	var last [numLevels]int
+	set := func(level, pos int) {
+		// 结尾标点后的引号可能超出限制
+		if pos <= limit {
+			last[level] = pos
+		}
+	}
+	for i := 0; i < limit; {
+		r, size := utf8.DecodeRune(text[i:])
+		next := i + size
+
+		switch {
+		case r == '\n':
+			if isParagraphBreak(text, i) {
+				set(levelParagraph, next)
+			} else {
+				set(levelSentence, next)
+			}
+		case r == '。' || r == '！' || r == '？' || r == '…':
+			set(levelSentence, skipClosers(text, next))
+		case r == '.' || r == '!' || r == '?':
+			if end := skipClosers(text, next); end < len(text) && isSpace(text, end) {
+				set(levelSentence, end)
+			}
+		case r == '，' || r == '、' || r == '；' || r == '：':
+			set(levelClause, next)
+		case r == ',' || r == ':' || (r == ';' && !isEntityEnd(text, i)):
+			if next < len(text) && isSpace(text, next) {
+				set(levelClause, next)
+			}
+		case unicode.IsSpace(r):
+			set(levelSpace, next)
+		}
+		i = next
+	}
+
+	for _, minPos := range []int{limit / 3, 1} {
+		for level := 0; level < numLevels; level++ {
+			if pos := last[level]; pos >= minPos && pos > 0 {
+				if pos = adjustSplitPointForXMLEntity(text, pos); pos > 0 {
+					return pos
+				}
+			}
+		}
+	}
+
+	// 没有合适的边界，退回到安全的 UTF-8 边界
+	splitAt := adjustSplitPointForXMLEntity(text, findSafeUTF8SplitPoint(text[:limit]))
+	if splitAt <= 0 {
+		splitAt = max(findSafeUTF8SplitPoint(text[:limit]), 1)
+	}
+	return splitAt
+}
+
+// isParagraphBreak 判断 text[i] 处的换行之前是否只隔着空白就是另一个换行
+func isParagraphBreak(text []byte, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		switch text[j] {
+		case ' ', '\t', '\r':
+			continue
+		case '\n':
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// closers 句末可能跟随的右引号和括号（含转义后的引号）
+var closers = [][]byte{
+	[]byte("&#34;"), []byte("&#39;"), []byte("&quot;"), []byte("&apos;"),
+	[]byte(")"), []byte("]"), []byte("”"), []byte("’"), []byte("」"), []byte("』"), []byte("）"), []byte("》"),
+}
+
+// skipClosers 跳过 text[i:] 开头的右引号和括号，返回之后的位置
+func skipClosers(text []byte, i int) int {
+	for {
+		matched := false
+		for _, c := range closers {
+			if bytes.HasPrefix(text[i:], c) {
+				i += len(c)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return i
+		}
+	}
+}
+
+// isSpace 判断 text[i:] 是否以空白字符开头
+func isSpace(text []byte, i int) bool {
+	r, _ := utf8.DecodeRune(text[i:])
+	return unicode.IsSpace(r)
+}
+
+// isEntityEnd 判断 text[i] 处的分号是否是 XML 实体的结尾
+func isEntityEnd(text []byte, i int) bool {
+	amp := bytes.LastIndexByte(text[:i], '&')
+	if amp < 0 || i-amp > 10 {
+		return false
+	}
+	return bytes.IndexFunc(text[amp:i], unicode.IsSpace) < 0
+}
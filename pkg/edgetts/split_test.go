@@ -0,0 +1,96 @@
+package edgetts
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSentenceSplitterPrefersParagraphs(t *testing.T) {
+	para1 := strings.Repeat("One sentence here. ", 5)
+	para2 := strings.Repeat("Another one there. ", 5)
+	chunks := SentenceSplitter{}.Split(para1+"\n\n"+para2, 150)
+	if len(chunks) != 2 {
+		t.Fatalf("chunks = %q", chunks)
+	}
+	if string(chunks[0]) != strings.TrimSpace(para1) {
+		t.Errorf("chunk 0 = %q", chunks[0])
+	}
+}
+
+func TestSentenceSplitterSentences(t *testing.T) {
+	text := "The first sentence is short. The second sentence, which has a clause, is a bit longer than the first."
+	chunks := SentenceSplitter{}.Split(text, 60)
+	if string(chunks[0]) != "The first sentence is short." {
+		t.Errorf("chunk 0 = %q", chunks[0])
+	}
+	if string(chunks[1]) != "The second sentence, which has a clause," {
+		t.Errorf("chunk 1 = %q", chunks[1])
+	}
+}
+
+func TestSentenceSplitterCJK(t *testing.T) {
+	sentence := "今天天气很好，我们去公园散步吧。"
+	text := strings.Repeat(sentence, 20)
+	chunks := SentenceSplitter{}.Split(text, 200)
+	if len(chunks) < 2 {
+		t.Fatalf("chunks = %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if len(c) > 200 || !utf8.Valid(c) {
+			t.Errorf("chunk %d invalid: %d bytes", i, len(c))
+		}
+		if !strings.HasSuffix(string(c), "。") {
+			t.Errorf("chunk %d does not end at a sentence: %q", i, c)
+		}
+	}
+	if got := string(bytes.Join(chunks, nil)); got != text {
+		t.Error("text changed")
+	}
+}
+
+func TestSentenceSplitterEllipsisAndQuotes(t *testing.T) {
+	text := EscapeXML(`He said "wait..." and left. ` + strings.Repeat("x", 30))
+	chunks := SentenceSplitter{}.Split(text, 30)
+	if string(chunks[0]) != EscapeXML(`He said "wait..."`) {
+		t.Errorf("chunk 0 = %q", chunks[0])
+	}
+}
+
+func TestSentenceSplitterKeepsEntities(t *testing.T) {
+	text := EscapeXML(strings.Repeat("a&b;c<d>e ", 50))
+	for _, c := range (SentenceSplitter{}).Split(text, 37) {
+		if i := bytes.LastIndexByte(c, '&'); i >= 0 && !bytes.Contains(c[i:], []byte(";")) {
+			t.Fatalf("entity cut in %q", c)
+		}
+	}
+}
+
+func TestWithChunkSize(t *testing.T) {
+	c, err := NewCommunicate(strings.Repeat("word ", 100), "", WithChunkSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.texts) < 5 {
+		t.Errorf("got %d chunks", len(c.texts))
+	}
+	for _, text := range c.texts {
+		if len(text) > 100 {
+			t.Errorf("chunk too long: %d", len(text))
+		}
+	}
+
+	if _, err := NewCommunicate("x", "", WithChunkSize(0)); err == nil {
+		t.Error("expected error for zero chunk size")
+	}
+
+	called := false
+	_, err = NewCommunicate("x", "", WithSplitter(SplitterFunc(func(s string, limit int) [][]byte {
+		called = true
+		return [][]byte{[]byte(s)}
+	})))
+	if err != nil || !called {
+		t.Errorf("custom splitter not used: %v", err)
+	}
+}
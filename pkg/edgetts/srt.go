@@ -16,6 +16,7 @@ type Subtitle struct {
 	Start   time.Duration
 	End     time.Duration
 	Content string
+	Speaker string // 说话人，SRT 中输出为 "Speaker: " 前缀
 }
 
 // ToSRT 将字幕转换为 SRT 格式块
@@ -25,6 +26,9 @@ func (s *Subtitle) ToSRT(eol string) string {
 	}
 
 	content := makeLegalContent(s.Content)
+	if s.Speaker != "" {
+		content = s.Speaker + ": " + content
+	}
 	if eol != "\n" {
 		content = strings.ReplaceAll(content, "\n", eol)
 	}
@@ -102,6 +106,7 @@ func sortAndReindex(subtitles []Subtitle, startIndex int, skip bool) []Subtitle
 			Start:   sub.Start,
 			End:     sub.End,
 			Content: sub.Content,
+			Speaker: sub.Speaker,
 		}
 		result = append(result, newSub)
 		idx++
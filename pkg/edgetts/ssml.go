@@ -0,0 +1,454 @@
+package edgetts
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// atomicSSMLElements 不能在内部切分的 SSML 元素
+var atomicSSMLElements = map[string]bool{
+	"break":    true,
+	"say-as":   true,
+	"sub":      true,
+	"phoneme":  true,
+	"mark":     true,
+	"bookmark": true,
+	"audio":    true,
+}
+
+// NewCommunicateSSML 使用 SSML 创建通信实例
+// ssml 可以是完整的 <speak> 文档，也可以是片段；片段会包装到 <speak> 中。
+// 文档中没有 <voice> 元素时按 voice 和韵律选项包装 <speak> 的内容。
+// 文档过长时在元素之间或文本中切分，并在每块的边界处重新打开和关闭外层元素。
+func NewCommunicateSSML(ssml string, voice string, opts ...CommunicateOption) (*Communicate, error) {
+	c, err := newCommunicate(voice, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := normalizeSSML(RemoveIncompatibleCharacters(ssml), c.ttsConfig)
+	if err != nil {
+		return nil, err
+	}
+	if c.characters, err = ssmlTextLength(doc); err != nil {
+		return nil, err
+	}
+	c.texts, err = SplitSSML(doc, c.chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	c.rawSSML = true
+	c.userSSML = true
+	return c, nil
+}
+
+// SSMLDocument 可以渲染为 SSML 文档的输入，例如 ssml.Builder
+type SSMLDocument interface {
+	SSML() (string, error)
+}
+
+// NewCommunicateDocument 使用 SSMLDocument 创建通信实例，等价于渲染后调用 NewCommunicateSSML
+func NewCommunicateDocument(doc SSMLDocument, voice string, opts ...CommunicateOption) (*Communicate, error) {
+	s, err := doc.SSML()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSSML, err)
+	}
+	return NewCommunicateSSML(s, voice, opts...)
+}
+
+// ssmlToken 带原始字节的 XML token
+type ssmlToken struct {
+	tok xml.Token
+	raw []byte
+}
+
+// tokenizeSSML 解析 SSML 并保留每个 token 的原始字节
+func tokenizeSSML(doc string) ([]ssmlToken, error) {
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	var tokens []ssmlToken
+	var prev int64
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSSML, err)
+		}
+		off := dec.InputOffset()
+		tokens = append(tokens, ssmlToken{tok: xml.CopyToken(tok), raw: []byte(doc[prev:off])})
+		prev = off
+	}
+	if err := checkWellFormed(doc); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// ssmlTextLength 返回 SSML 文档中朗读文本的字符数，不计标记和元素之间的空白
+func ssmlTextLength(doc string) (int, error) {
+	tokens, err := tokenizeSSML(doc)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, t := range tokens {
+		if text, ok := t.tok.(xml.CharData); ok && len(bytes.TrimSpace(text)) > 0 {
+			n += utf8.RuneCount(text)
+		}
+	}
+	return n, nil
+}
+
+// checkWellFormed 用严格模式完整解析一遍，检查标签配对和实体
+func checkWellFormed(doc string) error {
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	depth, roots := 0, 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSSML, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+				if t.Name.Local != "speak" {
+					return fmt.Errorf("%w: root element must be <speak>, got <%s>", ErrInvalidSSML, t.Name.Local)
+				}
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(t)) > 0 {
+				return fmt.Errorf("%w: text outside <speak>", ErrInvalidSSML)
+			}
+		}
+	}
+	if roots != 1 {
+		return fmt.Errorf("%w: expected exactly one <speak> root, got %d", ErrInvalidSSML, roots)
+	}
+	return nil
+}
+
+// isSSMLDocument 判断输入是否以 <speak> 为根元素
+func isSSMLDocument(s string) bool {
+	dec := xml.NewDecoder(strings.NewReader(s))
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return false
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t.Name.Local == "speak"
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return false
+			}
+		}
+	}
+}
+
+// normalizeSSML 校验 SSML，并把片段或缺少 <voice> 的文档补全为可直接发送的文档
+func normalizeSSML(s string, tc *TTSConfig) (string, error) {
+	if !isSSMLDocument(s) {
+		s = speakOpenTag + s + "</speak>"
+	}
+
+	tokens, err := tokenizeSSML(s)
+	if err != nil {
+		return "", err
+	}
+
+	// 查找 <speak> 的起止位置以及是否存在 <voice>
+	depth, hasVoice := 0, false
+	speakOpen, speakClose := -1, -1
+	for i, t := range tokens {
+		switch tok := t.tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				speakOpen = i
+			}
+			if tok.Name.Local == "voice" {
+				hasVoice = true
+			}
+			if !isSelfClosing(t.raw) {
+				depth++
+			}
+		case xml.EndElement:
+			if len(t.raw) == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				speakClose = i
+			}
+		}
+	}
+	if hasVoice {
+		return s, nil
+	}
+	if speakOpen < 0 || speakClose < 0 {
+		return "", fmt.Errorf("%w: empty <speak> document", ErrInvalidSSML)
+	}
+
+	var head, inner, tail strings.Builder
+	for i, t := range tokens {
+		switch {
+		case i <= speakOpen:
+			head.Write(t.raw)
+		case i < speakClose:
+			inner.Write(t.raw)
+		default:
+			tail.Write(t.raw)
+		}
+	}
+	return head.String() + voiceProsody(tc.withoutStyle(), inner.String()) + tail.String(), nil
+}
+
+// withoutExpressAs 去掉文档中的 mstts:express-as 元素，保留其中的内容
+func withoutExpressAs(doc string) (string, error) {
+	tokens, err := tokenizeSSML(doc)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, t := range tokens {
+		switch tok := t.tok.(type) {
+		case xml.StartElement:
+			if tok.Name.Space == "mstts" && tok.Name.Local == "express-as" {
+				continue
+			}
+		case xml.EndElement:
+			if tok.Name.Space == "mstts" && tok.Name.Local == "express-as" {
+				continue
+			}
+		}
+		b.Write(t.raw)
+	}
+	return b.String(), nil
+}
+
+// isSelfClosing 判断原始开始标签是否自闭合
+func isSelfClosing(raw []byte) bool {
+	return bytes.HasSuffix(bytes.TrimRight(raw, " \t\r\n"), []byte("/>"))
+}
+
+// qualifiedName 从原始开始标签中取出带前缀的元素名
+func qualifiedName(raw []byte) string {
+	raw = bytes.TrimPrefix(bytes.TrimSpace(raw), []byte("<"))
+	end := bytes.IndexAny(raw, " \t\r\n/>")
+	if end < 0 {
+		return string(raw)
+	}
+	return string(raw[:end])
+}
+
+// ssmlSplitter 按字节上限切分 SSML 文档
+type ssmlSplitter struct {
+	limit   int
+	open    [][]byte // 当前打开的元素（原始开始标签）
+	names   []string // 当前打开的元素名
+	body    bytes.Buffer
+	content bool     // 当前块是否已有可朗读内容
+	bodies  []string // 已完成的块（不含 <speak>）
+}
+
+// reopen 返回重新打开当前元素栈的标签
+func (sp *ssmlSplitter) reopen() string {
+	var b strings.Builder
+	for _, raw := range sp.open {
+		b.Write(raw)
+	}
+	return b.String()
+}
+
+// closers 返回关闭当前元素栈的标签
+func (sp *ssmlSplitter) closers() string {
+	var b strings.Builder
+	for i := len(sp.names) - 1; i >= 0; i-- {
+		b.WriteString("</" + sp.names[i] + ">")
+	}
+	return b.String()
+}
+
+// capacity 返回当前块还能容纳的字节数
+func (sp *ssmlSplitter) capacity() int {
+	return sp.limit - sp.body.Len() - len(sp.closers())
+}
+
+// flush 结束当前块，并在新块中重新打开元素栈
+func (sp *ssmlSplitter) flush() {
+	if sp.content {
+		sp.bodies = append(sp.bodies, sp.body.String()+sp.closers())
+	}
+	sp.body.Reset()
+	sp.body.WriteString(sp.reopen())
+	sp.content = false
+}
+
+// add 添加一个不可切分的单元
+func (sp *ssmlSplitter) add(raw []byte, content bool) {
+	if len(raw) > sp.capacity() && sp.content {
+		sp.flush()
+	}
+	sp.body.Write(raw)
+	sp.content = sp.content || content
+}
+
+// addText 添加文本，超出上限时优先在段落、句子、子句边界处切分
+func (sp *ssmlSplitter) addText(raw []byte) {
+	for len(raw) > 0 {
+		capacity := sp.capacity()
+		if len(raw) <= capacity {
+			sp.body.Write(raw)
+			sp.content = sp.content || len(bytes.TrimSpace(raw)) > 0
+			return
+		}
+
+		if sp.content && capacity < sp.limit/3 {
+			// 当前块剩余空间太小，直接换到新块，避免切出过短的片段
+			sp.flush()
+			continue
+		}
+
+		splitAt := 0
+		if capacity > 0 {
+			splitAt = findSentenceSplitPoint(raw, capacity)
+		}
+		if splitAt <= 0 {
+			if sp.content {
+				sp.flush()
+				continue
+			}
+			// 空块也放不下时强制切分，避免死循环
+			splitAt = max(findSafeUTF8SplitPoint(raw[:min(len(raw), max(capacity, 1))]), 1)
+			splitAt = max(adjustSplitPointForXMLEntity(raw, splitAt), 1)
+		}
+
+		sp.body.Write(raw[:splitAt])
+		sp.content = sp.content || len(bytes.TrimSpace(raw[:splitAt])) > 0
+		raw = raw[splitAt:]
+		sp.flush()
+	}
+}
+
+// SplitSSML 把 SSML 文档切分为不超过 limit 字节内容的多个完整文档
+// 不会在 break、say-as、sub、phoneme 等元素内部切分；
+// 切分点处会关闭当前打开的元素，并在下一块开头重新打开
+func SplitSSML(doc string, limit int) ([][]byte, error) {
+	if limit <= 0 {
+		return nil, errors.New("split limit must be positive")
+	}
+
+	tokens, err := tokenizeSSML(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &ssmlSplitter{limit: limit}
+	var prefix, suffix strings.Builder
+	depth, inBody := 0, false
+	// atomicDepth > 0 表示正在收集不可切分的元素
+	atomicDepth := 0
+	var atomic bytes.Buffer
+
+	for _, t := range tokens {
+		switch tok := t.tok.(type) {
+		case xml.StartElement:
+			selfClosing := isSelfClosing(t.raw)
+			switch {
+			case depth == 0:
+				prefix.Write(t.raw)
+				inBody = true
+			case atomicDepth > 0:
+				atomic.Write(t.raw)
+				if !selfClosing {
+					atomicDepth++
+				}
+			case atomicSSMLElements[tok.Name.Local]:
+				if selfClosing {
+					sp.add(t.raw, true)
+				} else {
+					atomic.Reset()
+					atomic.Write(t.raw)
+					atomicDepth = 1
+				}
+			default:
+				sp.add(t.raw, false)
+				if !selfClosing {
+					sp.open = append(sp.open, t.raw)
+					sp.names = append(sp.names, qualifiedName(t.raw))
+				}
+			}
+			if !selfClosing {
+				depth++
+			}
+
+		case xml.EndElement:
+			if len(t.raw) == 0 {
+				// 自闭合元素的结束 token 没有原始字节
+				continue
+			}
+			depth--
+			switch {
+			case depth == 0:
+				suffix.Write(t.raw)
+			case atomicDepth > 0:
+				atomic.Write(t.raw)
+				atomicDepth--
+				if atomicDepth == 0 {
+					sp.add(atomic.Bytes(), true)
+				}
+			default:
+				sp.body.Write(t.raw)
+				sp.open = sp.open[:len(sp.open)-1]
+				sp.names = sp.names[:len(sp.names)-1]
+			}
+
+		case xml.CharData:
+			switch {
+			case depth == 0 && !inBody:
+				prefix.Write(t.raw)
+			case depth == 0:
+				suffix.Write(t.raw)
+			case atomicDepth > 0:
+				atomic.Write(t.raw)
+			default:
+				sp.addText(t.raw)
+			}
+
+		default:
+			// 注释、处理指令等
+			switch {
+			case depth == 0 && !inBody:
+				prefix.Write(t.raw)
+			case depth == 0:
+				suffix.Write(t.raw)
+			case atomicDepth > 0:
+				atomic.Write(t.raw)
+			default:
+				sp.add(t.raw, false)
+			}
+		}
+	}
+
+	if sp.content {
+		sp.bodies = append(sp.bodies, sp.body.String())
+	}
+	chunks := make([][]byte, len(sp.bodies))
+	for i, body := range sp.bodies {
+		chunks[i] = []byte(prefix.String() + body + suffix.String())
+	}
+	return chunks, nil
+}
@@ -0,0 +1,256 @@
+// Package ssml 提供类型化的 SSML 构建器
+//
+// 构建器负责转义文本和属性，并自动闭合未结束的元素，保证输出是格式良好的 SSML：
+//
+//	doc, err := ssml.New().
+//		Voice("en-US-GuyNeural").
+//		Prosody(ssml.Prosody{Rate: "+10%"}).
+//		Text("Meet me at ").
+//		SayAs("time", "10:30").
+//		Break(500 * time.Millisecond).
+//		Emphasis("strong", "sharp").
+//		SSML()
+package ssml
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLang 默认的 xml:lang
+const DefaultLang = "en-US"
+
+// ErrUnbalanced End 调用次数多于打开的元素
+var ErrUnbalanced = errors.New("ssml: End called with no open element")
+
+// Prosody 韵律设置，空字段不输出
+type Prosody struct {
+	Pitch  string
+	Rate   string
+	Volume string
+}
+
+// Builder SSML 构建器
+// 方法返回构建器本身以便链式调用，第一个错误会被记录并由 SSML 返回
+type Builder struct {
+	lang  string
+	body  strings.Builder
+	stack []string
+	mstts bool
+	err   error
+}
+
+// New 创建构建器
+func New() *Builder {
+	return &Builder{lang: DefaultLang}
+}
+
+// Lang 设置根元素的 xml:lang
+func (b *Builder) Lang(lang string) *Builder {
+	if lang == "" {
+		b.fail(errors.New("ssml: empty lang"))
+		return b
+	}
+	b.lang = lang
+	return b
+}
+
+// fail 记录第一个错误
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// open 打开一个需要 End 闭合的元素
+func (b *Builder) open(name string, attrs ...string) *Builder {
+	b.body.WriteString(startTag(name, attrs...))
+	b.stack = append(b.stack, name)
+	return b
+}
+
+// element 写入一个包含文本的完整元素
+func (b *Builder) element(name, text string, attrs ...string) *Builder {
+	b.body.WriteString(startTag(name, attrs...))
+	b.body.WriteString(escape(text))
+	b.body.WriteString("</" + name + ">")
+	return b
+}
+
+// Voice 打开 voice 元素
+func (b *Builder) Voice(name string) *Builder {
+	if name == "" {
+		b.fail(errors.New("ssml: empty voice name"))
+	}
+	return b.open("voice", "name", name)
+}
+
+// Prosody 打开 prosody 元素
+func (b *Builder) Prosody(p Prosody) *Builder {
+	return b.open("prosody", "pitch", p.Pitch, "rate", p.Rate, "volume", p.Volume)
+}
+
+// ExpressAs 打开 mstts:express-as 元素，设置说话风格、强度和角色，空值和 0 不输出
+func (b *Builder) ExpressAs(style string, degree float64, role string) *Builder {
+	if style == "" && role == "" {
+		b.fail(errors.New("ssml: express-as needs a style or role"))
+	}
+	if degree < 0 || degree > 2 {
+		b.fail(fmt.Errorf("ssml: style degree %v out of range 0.01-2", degree))
+	}
+	b.mstts = true
+	styleDegree := ""
+	if degree != 0 {
+		styleDegree = strconv.FormatFloat(degree, 'f', -1, 64)
+	}
+	return b.open("mstts:express-as", "style", style, "styledegree", styleDegree, "role", role)
+}
+
+// Paragraph 打开 p 元素
+func (b *Builder) Paragraph() *Builder {
+	return b.open("p")
+}
+
+// Sentence 打开 s 元素
+func (b *Builder) Sentence() *Builder {
+	return b.open("s")
+}
+
+// End 闭合最近打开的元素
+func (b *Builder) End() *Builder {
+	if len(b.stack) == 0 {
+		b.fail(ErrUnbalanced)
+		return b
+	}
+	name := b.stack[len(b.stack)-1]
+	b.stack = b.stack[:len(b.stack)-1]
+	b.body.WriteString("</" + name + ">")
+	return b
+}
+
+// Text 写入转义后的文本
+func (b *Builder) Text(text string) *Builder {
+	b.body.WriteString(escape(text))
+	return b
+}
+
+// Break 插入停顿
+func (b *Builder) Break(d time.Duration) *Builder {
+	if d < 0 {
+		b.fail(fmt.Errorf("ssml: negative break duration %v", d))
+		return b
+	}
+	b.body.WriteString("<break time='" + formatDuration(d) + "'/>")
+	return b
+}
+
+// BreakStrength 按强度插入停顿，strength 为 none、x-weak、weak、medium、strong 或 x-strong
+func (b *Builder) BreakStrength(strength string) *Builder {
+	switch strength {
+	case "none", "x-weak", "weak", "medium", "strong", "x-strong":
+	default:
+		b.fail(fmt.Errorf("ssml: invalid break strength %q", strength))
+		return b
+	}
+	b.body.WriteString("<break strength='" + strength + "'/>")
+	return b
+}
+
+// SayAs 按指定类型朗读文本，例如 date、time、cardinal、characters
+func (b *Builder) SayAs(interpretAs, text string) *Builder {
+	return b.SayAsFormat(interpretAs, "", text)
+}
+
+// SayAsFormat 按指定类型和格式朗读文本，例如 SayAsFormat("date", "mdy", "10/16/2026")
+func (b *Builder) SayAsFormat(interpretAs, format, text string) *Builder {
+	if interpretAs == "" {
+		b.fail(errors.New("ssml: empty say-as interpret-as"))
+		return b
+	}
+	return b.element("say-as", text, "interpret-as", interpretAs, "format", format)
+}
+
+// Emphasis 强调文本，level 为 reduced、none、moderate 或 strong，为空时使用服务默认值
+func (b *Builder) Emphasis(level, text string) *Builder {
+	switch level {
+	case "", "reduced", "none", "moderate", "strong":
+	default:
+		b.fail(fmt.Errorf("ssml: invalid emphasis level %q", level))
+		return b
+	}
+	return b.element("emphasis", text, "level", level)
+}
+
+// Sub 用 alias 替换 text 的读音
+func (b *Builder) Sub(alias, text string) *Builder {
+	return b.element("sub", text, "alias", alias)
+}
+
+// Phoneme 用音标指定 text 的读音，alphabet 例如 ipa、sapi、ups
+func (b *Builder) Phoneme(alphabet, ph, text string) *Builder {
+	if ph == "" {
+		b.fail(errors.New("ssml: empty phoneme"))
+		return b
+	}
+	return b.element("phoneme", text, "alphabet", alphabet, "ph", ph)
+}
+
+// Err 返回构建过程中的第一个错误
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// String 返回 SSML 文档，未闭合的元素会被自动闭合
+func (b *Builder) String() string {
+	var sb strings.Builder
+	sb.WriteString("<speak version='1.0' xmlns='http://www.w3.org/2001/10/synthesis'")
+	if b.mstts {
+		sb.WriteString(" xmlns:mstts='https://www.w3.org/2001/mstts'")
+	}
+	sb.WriteString(" xml:lang='" + escape(b.lang) + "'>")
+	sb.WriteString(b.body.String())
+	for i := len(b.stack) - 1; i >= 0; i-- {
+		sb.WriteString("</" + b.stack[i] + ">")
+	}
+	sb.WriteString("</speak>")
+	return sb.String()
+}
+
+// SSML 返回 SSML 文档和构建过程中的第一个错误
+func (b *Builder) SSML() (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	return b.String(), nil
+}
+
+// startTag 生成开始标签，attrs 为名称和值交替的列表，值为空的属性不输出
+func startTag(name string, attrs ...string) string {
+	var sb strings.Builder
+	sb.WriteString("<" + name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] == "" {
+			continue
+		}
+		sb.WriteString(" " + attrs[i] + "='" + escape(attrs[i+1]) + "'")
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
+
+// escape 转义文本和属性值，与 edgetts.EscapeXML 保持一致
+func escape(s string) string {
+	return html.EscapeString(s)
+}
+
+// formatDuration 把停顿时长格式化为 SSML 时间值
+func formatDuration(d time.Duration) string {
+	if d%time.Second == 0 && d > 0 {
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+	return fmt.Sprintf("%dms", d/time.Millisecond)
+}
@@ -0,0 +1,109 @@
+package ssml_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/ssml"
+)
+
+func TestBuilderMatchesMKSSML(t *testing.T) {
+	tc := &edgetts.TTSConfig{Voice: "en-US-EmmaMultilingualNeural", Rate: "+10%", Volume: "-5%", Pitch: "+2Hz"}
+	text := `Tom & Jerry said "<hi>" it's fine`
+
+	got := ssml.New().
+		Voice(tc.Voice).
+		Prosody(ssml.Prosody{Pitch: tc.Pitch, Rate: tc.Rate, Volume: tc.Volume}).
+		Text(text).
+		String()
+	if want := edgetts.MKSSML(tc, edgetts.EscapeXML(text)); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestBuilderMatchesMKSSMLWithStyle(t *testing.T) {
+	tc := &edgetts.TTSConfig{Voice: "v", Rate: "+0%", Volume: "+0%", Pitch: "+0Hz", Style: "cheerful", StyleDegree: 1.5, Role: "Girl"}
+
+	got := ssml.New().
+		Voice(tc.Voice).
+		ExpressAs(tc.Style, tc.StyleDegree, tc.Role).
+		Prosody(ssml.Prosody{Pitch: tc.Pitch, Rate: tc.Rate, Volume: tc.Volume}).
+		Text("hi").
+		String()
+	if want := edgetts.MKSSML(tc, "hi"); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestBuilderElements(t *testing.T) {
+	doc, err := ssml.New().
+		Lang("en-GB").
+		Voice("en-GB-SoniaNeural").
+		Paragraph().
+		Text("On ").
+		SayAsFormat("date", "mdy", "10/16/2026").
+		Break(500*time.Millisecond).
+		Emphasis("strong", "R&D").
+		Sub("World Wide Web Consortium", "W3C").
+		Phoneme("ipa", "təˈmeɪtoʊ", "tomato").
+		Break(2 * time.Second).
+		End().
+		SSML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"xml:lang='en-GB'",
+		"<p>On <say-as interpret-as='date' format='mdy'>10/16/2026</say-as>",
+		"<break time='500ms'/>",
+		"<emphasis level='strong'>R&amp;D</emphasis>",
+		"<sub alias='World Wide Web Consortium'>W3C</sub>",
+		"<phoneme alphabet='ipa' ph='təˈmeɪtoʊ'>tomato</phoneme>",
+		"<break time='2s'/></p></voice></speak>",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("missing %q in %s", want, doc)
+		}
+	}
+
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("not well-formed: %v", err)
+		}
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *ssml.Builder
+	}{
+		{"unbalanced", ssml.New().Text("a").End()},
+		{"negative break", ssml.New().Break(-time.Second)},
+		{"empty voice", ssml.New().Voice("")},
+		{"bad emphasis", ssml.New().Emphasis("loud", "x")},
+		{"bad strength", ssml.New().BreakStrength("huge")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.b.SSML(); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+	if _, err := ssml.New().End().SSML(); !errors.Is(err, ssml.ErrUnbalanced) {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := edgetts.NewCommunicateDocument(ssml.New().End(), ""); !errors.Is(err, edgetts.ErrInvalidSSML) {
+		t.Errorf("NewCommunicateDocument err = %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package edgetts
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// ssmlText 返回 SSML 中所有文本内容
+func ssmlText(t *testing.T, doc string) string {
+	t.Helper()
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	var b strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return b.String()
+		}
+		if err != nil {
+			t.Fatalf("chunk is not well-formed: %v\n%s", err, doc)
+		}
+		if cd, ok := tok.(xml.CharData); ok {
+			b.Write(cd)
+		}
+	}
+}
+
+func TestSplitSSMLReopensElements(t *testing.T) {
+	doc := speakOpenTag +
+		"<voice name='en-US-GuyNeural'><prosody rate='+10%'>" +
+		strings.Repeat("alpha beta ", 40) +
+		"<say-as interpret-as='cardinal'>12345</say-as> " +
+		"<break time='500ms'/>" +
+		strings.Repeat("gamma delta ", 40) +
+		"</prosody></voice></speak>"
+
+	chunks, err := SplitSSML(doc, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 3 {
+		t.Fatalf("got %d chunks", len(chunks))
+	}
+
+	var text strings.Builder
+	for i, chunk := range chunks {
+		s := string(chunk)
+		if !strings.HasPrefix(s, speakOpenTag+"<voice name='en-US-GuyNeural'><prosody rate='+10%'>") {
+			t.Errorf("chunk %d does not reopen elements: %s", i, s)
+		}
+		if !strings.HasSuffix(s, "</prosody></voice></speak>") {
+			t.Errorf("chunk %d does not close elements: %s", i, s)
+		}
+		if strings.Count(s, "<say-as") != strings.Count(s, "</say-as>") {
+			t.Errorf("chunk %d splits say-as: %s", i, s)
+		}
+		text.WriteString(ssmlText(t, s))
+	}
+	if got, want := text.String(), ssmlText(t, doc); got != want {
+		t.Errorf("text changed:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestSplitSSMLShortDocument(t *testing.T) {
+	doc := speakOpenTag + "<voice name='x'>hi <emphasis>there</emphasis></voice></speak>"
+	chunks, err := SplitSSML(doc, DefaultChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || string(chunks[0]) != doc {
+		t.Fatalf("chunks = %q", chunks)
+	}
+}
+
+func TestNormalizeSSML(t *testing.T) {
+	tc := &TTSConfig{Voice: "v", Rate: "+0%", Volume: "+0%", Pitch: "+0Hz"}
+	tests := []struct {
+		name, in, want string
+	}{
+		{"fragment", "hi <break time='1s'/>", MKSSML(tc, "hi <break time='1s'/>")},
+		{"fragment with voice", "<voice name='a'>hi</voice>", speakOpenTag + "<voice name='a'>hi</voice></speak>"},
+		{"document", "<speak version='1.0'><voice name='a'>hi</voice></speak>", "<speak version='1.0'><voice name='a'>hi</voice></speak>"},
+		{"document without voice", "<speak version='1.0'>hi</speak>", "<speak version='1.0'>" + voiceProsody(tc, "hi") + "</speak>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeSSML(tt.in, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCommunicateSSMLInvalid(t *testing.T) {
+	for _, in := range []string{
+		"<voice name='a'>unclosed",
+		"a & b",
+		"<speak>one</speak><speak>two</speak>",
+		"<p>mismatched</s>",
+	} {
+		if _, err := NewCommunicateSSML(in, ""); !errors.Is(err, ErrInvalidSSML) {
+			t.Errorf("%q: err = %v", in, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package edgetts
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// KnownStyles 已知的说话风格（mstts:express-as 的 style 属性）
+// 具体语音支持的风格是其中的子集
+var KnownStyles = []string{
+	"advertisement_upbeat", "affectionate", "angry", "assistant", "calm", "chat",
+	"cheerful", "customerservice", "depressed", "disgruntled", "documentary-narration",
+	"embarrassed", "empathetic", "envious", "excited", "fearful", "friendly", "gentle",
+	"hopeful", "lyrical", "narration-professional", "narration-relaxed", "newscast",
+	"newscast-casual", "newscast-formal", "poetry-reading", "sad", "serious", "shouting",
+	"sports_commentary", "sports_commentary_excited", "terrified", "unfriendly", "whispering",
+}
+
+// KnownRoles 已知的角色扮演（mstts:express-as 的 role 属性）
+var KnownRoles = []string{
+	"Girl", "Boy", "YoungAdultFemale", "YoungAdultMale",
+	"OlderAdultFemale", "OlderAdultMale", "SeniorFemale", "SeniorMale",
+}
+
+// WithStyle 设置说话风格，例如 cheerful、sad，对纯文本、Markdown、HTML 和对话输入生效，
+// 不影响 NewCommunicateSSML 的输入；服务端不支持时去掉风格重新合成，见 ErrStyleDropped
+func WithStyle(style string) CommunicateOption {
+	return func(c *Communicate) {
+		c.ttsConfig.Style = style
+	}
+}
+
+// WithStyleDegree 设置说话风格强度，范围 0.01~2，0 表示使用默认强度
+func WithStyleDegree(degree float64) CommunicateOption {
+	return func(c *Communicate) {
+		c.ttsConfig.StyleDegree = degree
+	}
+}
+
+// WithRole 设置角色扮演，例如 Girl、SeniorMale，生效范围与 WithStyle 相同
+func WithRole(role string) CommunicateOption {
+	return func(c *Communicate) {
+		c.ttsConfig.Role = role
+	}
+}
+
+// WithVoiceMetadata 提供语音元数据（例如 VoicesManager.Voices），按所选语音的 StyleList 和 RolePlayList 校验说话风格和角色
+// 语音不在列表中或没有 StyleList、RolePlayList 时只按 KnownStyles 和 KnownRoles 校验
+func WithVoiceMetadata(voices ...Voice) CommunicateOption {
+	return func(c *Communicate) {
+		c.voiceMetadata = voices
+	}
+}
+
+// hasStyle 判断配置是否需要 mstts:express-as
+func (tc *TTSConfig) hasStyle() bool {
+	return tc.Style != "" || tc.Role != ""
+}
+
+// withoutStyle 返回去掉说话风格的配置副本
+func (tc *TTSConfig) withoutStyle() *TTSConfig {
+	plain := *tc
+	plain.Style = ""
+	plain.StyleDegree = 0
+	plain.Role = ""
+	return &plain
+}
+
+// validateStyle 校验说话风格、强度和角色
+func validateStyle(tc *TTSConfig) error {
+	if tc.Style != "" && !slices.Contains(KnownStyles, tc.Style) {
+		return fmt.Errorf("%w: %s", ErrInvalidStyle, tc.Style)
+	}
+	if tc.StyleDegree != 0 && (tc.StyleDegree < 0.01 || tc.StyleDegree > 2) {
+		return fmt.Errorf("%w: degree %v out of range 0.01-2", ErrInvalidStyle, tc.StyleDegree)
+	}
+	if tc.StyleDegree != 0 && tc.Style == "" {
+		return fmt.Errorf("%w: degree set without style", ErrInvalidStyle)
+	}
+	if tc.Role != "" && !slices.Contains(KnownRoles, tc.Role) {
+		return fmt.Errorf("%w: %s", ErrInvalidRole, tc.Role)
+	}
+	return nil
+}
+
+// validateVoiceStyle 按 voices 中所选语音的元数据校验说话风格和角色，tc 已经过 ValidateTTSConfig
+func validateVoiceStyle(tc *TTSConfig, voices []Voice) error {
+	if !tc.hasStyle() {
+		return nil
+	}
+	i := slices.IndexFunc(voices, func(v Voice) bool {
+		return v.Name == tc.Voice || fullVoiceName(v.ShortName) == tc.Voice
+	})
+	if i < 0 {
+		return nil
+	}
+	v := voices[i]
+	if tc.Style != "" && len(v.StyleList) > 0 && !slices.Contains(v.StyleList, tc.Style) {
+		return fmt.Errorf("%w: %s is not supported by %s", ErrInvalidStyle, tc.Style, v.ShortName)
+	}
+	if tc.Role != "" && len(v.RolePlayList) > 0 && !slices.Contains(v.RolePlayList, tc.Role) {
+		return fmt.Errorf("%w: %s is not supported by %s", ErrInvalidRole, tc.Role, v.ShortName)
+	}
+	return nil
+}
+
+// expressAs 用 mstts:express-as 元素包装内容
+func expressAs(tc *TTSConfig, content string) string {
+	tag := "<mstts:express-as"
+	if tc.Style != "" {
+		tag += " style='" + tc.Style + "'"
+	}
+	if tc.StyleDegree != 0 {
+		tag += " styledegree='" + strconv.FormatFloat(tc.StyleDegree, 'f', -1, 64) + "'"
+	}
+	if tc.Role != "" {
+		tag += " role='" + tc.Role + "'"
+	}
+	return tag + ">" + content + "</mstts:express-as>"
+}
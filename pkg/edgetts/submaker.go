@@ -40,6 +40,7 @@ func (sm *SubMaker) Feed(msg TTSChunk) error {
 		Start:   time.Duration(startMicros) * time.Microsecond,
 		End:     time.Duration(endMicros) * time.Microsecond,
 		Content: msg.Text,
+		Speaker: msg.Speaker,
 	}
 
 	sm.Cues = append(sm.Cues, subtitle)
@@ -51,6 +52,11 @@ func (sm *SubMaker) GetSRT() string {
 	return ComposeSRT(sm.Cues, true, 1, "")
 }
 
+// GetVTT 获取 WebVTT 格式的字幕
+func (sm *SubMaker) GetVTT() string {
+	return ComposeVTT(sm.Cues)
+}
+
 // String 返回 SRT 格式的字幕
 func (sm *SubMaker) String() string {
 	return sm.GetSRT()
@@ -0,0 +1,146 @@
+package edgetts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// Synthesizer 长期持有的合成客户端，保存默认的语音、韵律、传输层、重试和缓存设置
+// 可以被多个 goroutine 并发使用，每次合成创建独立的 Communicate
+type Synthesizer struct {
+	opts []CommunicateOption
+}
+
+// NewSynthesizer 创建合成客户端，opts 作为每次合成的默认选项，会立即校验
+func NewSynthesizer(opts ...CommunicateOption) (*Synthesizer, error) {
+	if _, err := newCommunicate("", opts...); err != nil {
+		return nil, err
+	}
+	return &Synthesizer{opts: append([]CommunicateOption(nil), opts...)}, nil
+}
+
+// options 返回默认选项加上本次调用的覆盖选项
+func (s *Synthesizer) options(overrides []CommunicateOption) []CommunicateOption {
+	opts := make([]CommunicateOption, 0, len(s.opts)+len(overrides))
+	return append(append(opts, s.opts...), overrides...)
+}
+
+// NewCommunicate 使用默认选项创建单次使用的通信实例，overrides 覆盖默认选项
+func (s *Synthesizer) NewCommunicate(text string, overrides ...CommunicateOption) (*Communicate, error) {
+	return NewCommunicate(text, "", s.options(overrides)...)
+}
+
+// NewCommunicateSSML 使用默认选项创建 SSML 输入的通信实例
+func (s *Synthesizer) NewCommunicateSSML(ssml string, overrides ...CommunicateOption) (*Communicate, error) {
+	return NewCommunicateSSML(ssml, "", s.options(overrides)...)
+}
+
+// Stream 流式合成文本，不使用缓存；创建通信实例失败时错误通过 error channel 返回
+func (s *Synthesizer) Stream(ctx context.Context, text string, overrides ...CommunicateOption) (<-chan TTSChunk, <-chan error) {
+	c, err := s.NewCommunicate(text, overrides...)
+	if err != nil {
+		chunkCh := make(chan TTSChunk)
+		errCh := make(chan error, 1)
+		errCh <- err
+		close(errCh)
+		close(chunkCh)
+		return chunkCh, errCh
+	}
+	return c.Stream(ctx)
+}
+
+// Synthesize 合成文本并返回完整的音频和边界
+// 设置了 WithCache 时先查找缓存，没有警告的结果会被缓存
+func (s *Synthesizer) Synthesize(ctx context.Context, text string, overrides ...CommunicateOption) (*SynthesisResult, error) {
+	c, err := s.NewCommunicate(text, overrides...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Synthesize(ctx)
+}
+
+// SynthesisResult 一次合成的结果
+// 缓存命中时结果与缓存共享数据，调用方不能修改 Audio 和 Boundaries
+type SynthesisResult struct {
+	Audio      []byte
+	Boundaries []TTSChunk // WordBoundary 和 SentenceBoundary
+	Format     OutputFormat
+	Warnings   []error
+	Cached     bool // 结果来自缓存
+}
+
+// subMaker 用边界生成字幕
+func (r *SynthesisResult) subMaker() *SubMaker {
+	sm := NewSubMaker()
+	for _, b := range r.Boundaries {
+		sm.Feed(b)
+	}
+	return sm
+}
+
+// SRT 返回 SRT 字幕
+func (r *SynthesisResult) SRT() string {
+	return r.subMaker().GetSRT()
+}
+
+// VTT 返回 WebVTT 字幕
+func (r *SynthesisResult) VTT() string {
+	return r.subMaker().GetVTT()
+}
+
+// WriteTo 把音频写入 w，实现 io.WriterTo
+func (r *SynthesisResult) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(r.Audio)
+	return int64(n), err
+}
+
+// Synthesize 合成全部文本块并返回完整结果，设置了 WithCache 时使用并更新缓存
+func (c *Communicate) Synthesize(ctx context.Context) (*SynthesisResult, error) {
+	var key string
+	if c.cache != nil {
+		key = c.cacheKey()
+		if r, ok := c.cache.Get(key); ok {
+			cached := *r
+			cached.Cached = true
+			return &cached, nil
+		}
+	}
+
+	chunks, err := c.StreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r := &SynthesisResult{Format: c.ttsConfig.Format, Warnings: c.Warnings()}
+	for _, chunk := range chunks {
+		if chunk.Type == "audio" {
+			r.Audio = append(r.Audio, chunk.Data...)
+		} else {
+			r.Boundaries = append(r.Boundaries, chunk)
+		}
+	}
+	if c.cache != nil && len(r.Warnings) == 0 {
+		c.cache.Add(key, r)
+	}
+	return r, nil
+}
+
+// cacheKey 由请求的 SSML、输出格式、边界类型、说话人和原文计算缓存键
+func (c *Communicate) cacheKey() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", c.ttsConfig.Format, c.ttsConfig.Boundary)
+	for i, text := range c.texts {
+		var speaker string
+		if i < len(c.speakers) {
+			speaker = c.speakers[i]
+		}
+		fmt.Fprintf(h, "%d\x00%s\x00%s\x00", i, c.ssml(text), speaker)
+	}
+	if c.tracker != nil {
+		// 原文不同的文本处理后可能相同，边界的 SourceText 却不同
+		fmt.Fprintf(h, "source\x00%s", c.tracker.source)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
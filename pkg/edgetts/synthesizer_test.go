@@ -0,0 +1,158 @@
+package edgetts_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+func TestSynthesizerConcurrent(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	synth, err := edgetts.NewSynthesizer(
+		edgetts.WithClient(srv.Client()),
+		edgetts.WithVoice("en-US-GuyNeural"),
+		edgetts.WithRate("+10%"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			text := fmt.Sprintf("request number %d", i)
+			r, err := synth.Synthesize(ctx, text)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if got := string(r.Audio); got != strings.ReplaceAll(text, " ", "") {
+				errs <- fmt.Errorf("audio %q for %q", got, text)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 8 {
+		t.Fatalf("requests = %d", len(reqs))
+	}
+	for _, req := range reqs {
+		if !strings.Contains(req.SSML, "GuyNeural") || !strings.Contains(req.SSML, "rate='+10%'") {
+			t.Errorf("defaults not applied: %s", req.SSML)
+		}
+	}
+}
+
+func TestSynthesizerOverrides(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	synth, err := edgetts.NewSynthesizer(edgetts.WithClient(srv.Client()), edgetts.WithPitch("+5Hz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := synth.Synthesize(context.Background(), "one two", edgetts.WithPitch("-5Hz"), edgetts.WithBoundary("WordBoundary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Boundaries) != 2 || !strings.Contains(r.SRT(), "one") {
+		t.Errorf("boundaries = %+v", r.Boundaries)
+	}
+	if ssml := srv.Requests()[0].SSML; !strings.Contains(ssml, "pitch='-5Hz'") {
+		t.Errorf("override not applied: %s", ssml)
+	}
+
+	// 覆盖选项不影响后续调用
+	if _, err := synth.Synthesize(context.Background(), "three"); err != nil {
+		t.Fatal(err)
+	}
+	if ssml := srv.Requests()[1].SSML; !strings.Contains(ssml, "pitch='+5Hz'") {
+		t.Errorf("default pitch lost: %s", ssml)
+	}
+
+	if _, err := edgetts.NewSynthesizer(edgetts.WithRate("fast")); err == nil {
+		t.Error("expected invalid default options to fail")
+	}
+}
+
+func TestSynthesizerCache(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	cache := edgetts.NewMemoryCache(1<<20, time.Hour)
+	synth, err := edgetts.NewSynthesizer(edgetts.WithClient(srv.Client()), edgetts.WithCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	first, err := synth.Synthesize(ctx, "cache me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := synth.Synthesize(ctx, "cache me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Cached || !second.Cached || string(second.Audio) != string(first.Audio) {
+		t.Errorf("first cached = %v, second cached = %v", first.Cached, second.Cached)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+
+	// 不同的语音是不同的缓存项，WithCache(nil) 跳过缓存
+	if r, _ := synth.Synthesize(ctx, "cache me", edgetts.WithVoice("en-US-GuyNeural")); r.Cached {
+		t.Error("different voice hit the cache")
+	}
+	if r, _ := synth.Synthesize(ctx, "cache me", edgetts.WithCache(nil)); r.Cached {
+		t.Error("WithCache(nil) hit the cache")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("cache len = %d", cache.Len())
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	cache := edgetts.NewMemoryCache(10, 0)
+	cache.Add("a", &edgetts.SynthesisResult{Audio: []byte("12345")})
+	cache.Add("b", &edgetts.SynthesisResult{Audio: []byte("12345")})
+	cache.Get("a")
+	cache.Add("c", &edgetts.SynthesisResult{Audio: []byte("123")})
+	if _, ok := cache.Get("b"); ok {
+		t.Error("least recently used entry not evicted")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("recently used entry evicted")
+	}
+	cache.Add("big", &edgetts.SynthesisResult{Audio: make([]byte, 11)})
+	if _, ok := cache.Get("big"); ok {
+		t.Error("oversized entry cached")
+	}
+
+	expiring := edgetts.NewMemoryCache(10, time.Nanosecond)
+	expiring.Add("a", &edgetts.SynthesisResult{Audio: []byte("1")})
+	time.Sleep(time.Millisecond)
+	if _, ok := expiring.Get("a"); ok {
+		t.Error("expired entry returned")
+	}
+}
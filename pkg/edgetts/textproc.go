@@ -0,0 +1,225 @@
+package edgetts
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Edit 一次文本替换：把源文本的 [Start, End) 字节替换为 Text
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// TextProcessor 合成前的文本变换
+// Process 返回按 Start 升序排列且互不重叠的替换，未被替换的文本保持不变，
+// 由处理链负责记录位置映射
+type TextProcessor interface {
+	Process(text string) []Edit
+}
+
+// TextProcessorFunc 函数形式的 TextProcessor
+type TextProcessorFunc func(text string) []Edit
+
+// Process 实现 TextProcessor
+func (f TextProcessorFunc) Process(text string) []Edit {
+	return f(text)
+}
+
+// RegexpProcessor 把 re 的每个匹配替换为 replace 的返回值
+// replace 返回 ok 为 false 时保留原文
+func RegexpProcessor(re *regexp.Regexp, replace func(match []string) (string, bool)) TextProcessor {
+	return TextProcessorFunc(func(text string) []Edit {
+		var edits []Edit
+		for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+			match := make([]string, len(loc)/2)
+			for i := range match {
+				if loc[2*i] >= 0 {
+					match[i] = text[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			if s, ok := replace(match); ok && s != match[0] {
+				edits = append(edits, Edit{Start: loc[0], End: loc[1], Text: s})
+			}
+		}
+		return edits
+	})
+}
+
+// WithTextProcessors 设置合成前的文本处理链，按顺序执行
+// 处理发生在 RemoveIncompatibleCharacters 之后、EscapeXML 之前，仅对 NewCommunicate 的输入生效，
+// Markdown 和 HTML 输入只处理去掉标记后要朗读的文本
+func WithTextProcessors(procs ...TextProcessor) CommunicateOption {
+	return func(c *Communicate) {
+		c.processors = append(c.processors, procs...)
+	}
+}
+
+var (
+	processorsMu sync.RWMutex
+	processors   = map[string]TextProcessor{}
+)
+
+// RegisterTextProcessor 按名称注册文本处理器，供命令行等按名称引用，重复注册会覆盖
+func RegisterTextProcessor(name string, p TextProcessor) {
+	processorsMu.Lock()
+	defer processorsMu.Unlock()
+	processors[name] = p
+}
+
+// LookupTextProcessor 按名称查找已注册的文本处理器
+func LookupTextProcessor(name string) (TextProcessor, bool) {
+	processorsMu.RLock()
+	defer processorsMu.RUnlock()
+	p, ok := processors[name]
+	return p, ok
+}
+
+// TextProcessorNames 返回已注册的文本处理器名称
+func TextProcessorNames() []string {
+	processorsMu.RLock()
+	defer processorsMu.RUnlock()
+	names := make([]string, 0, len(processors))
+	for name := range processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// segment 输出文本 [outStart, outEnd) 对应源文本 [srcStart, srcEnd)
+// identity 为 true 时两者逐字节对应，否则整体对应
+type segment struct {
+	outStart, outEnd int
+	srcStart, srcEnd int
+	identity         bool
+}
+
+// OffsetMap 处理后文本到源文本的位置映射
+type OffsetMap struct {
+	// layers 每个处理器一层，按执行顺序排列
+	layers [][]segment
+}
+
+// Source 返回处理后文本 [start, end) 对应的源文本范围
+func (m *OffsetMap) Source(start, end int) (int, int) {
+	if m == nil {
+		return start, end
+	}
+	for i := len(m.layers) - 1; i >= 0; i-- {
+		start, end = mapRange(m.layers[i], start, end)
+	}
+	return start, end
+}
+
+// mapRange 通过一层映射转换范围
+func mapRange(segs []segment, start, end int) (int, int) {
+	if len(segs) == 0 {
+		return start, end
+	}
+	find := func(pos int) segment {
+		i := sort.Search(len(segs), func(i int) bool { return segs[i].outEnd > pos })
+		if i == len(segs) {
+			i--
+		}
+		return segs[i]
+	}
+
+	s := find(start)
+	if s.identity {
+		start = s.srcStart + (start - s.outStart)
+	} else {
+		start = s.srcStart
+	}
+
+	if end <= 0 {
+		return start, start
+	}
+	e := find(end - 1)
+	if e.identity {
+		end = e.srcStart + (end - e.outStart)
+	} else {
+		end = e.srcEnd
+	}
+	return start, max(start, end)
+}
+
+// applyEdits 执行替换，返回新文本和本层映射
+func applyEdits(text string, edits []Edit) (string, []segment, error) {
+	var b strings.Builder
+	var segs []segment
+	pos := 0
+	keep := func(end int) {
+		if end > pos {
+			out := b.Len()
+			b.WriteString(text[pos:end])
+			segs = append(segs, segment{out, b.Len(), pos, end, true})
+		}
+	}
+	for _, e := range edits {
+		if e.Start < pos || e.End < e.Start || e.End > len(text) {
+			return "", nil, fmt.Errorf("invalid edit [%d, %d) at position %d", e.Start, e.End, pos)
+		}
+		keep(e.Start)
+		out := b.Len()
+		b.WriteString(e.Text)
+		segs = append(segs, segment{out, b.Len(), e.Start, e.End, false})
+		pos = e.End
+	}
+	keep(len(text))
+	return b.String(), segs, nil
+}
+
+// ProcessText 依次执行处理器，返回处理后的文本和到原文的位置映射
+func ProcessText(text string, procs ...TextProcessor) (string, *OffsetMap, error) {
+	m := &OffsetMap{}
+	for i, p := range procs {
+		edits := p.Process(text)
+		if len(edits) == 0 {
+			continue
+		}
+		out, segs, err := applyEdits(text, edits)
+		if err != nil {
+			return "", nil, fmt.Errorf("text processor %d: %w", i, err)
+		}
+		text = out
+		m.layers = append(m.layers, segs)
+	}
+	return text, m, nil
+}
+
+// sourceMap 处理后文本到源文本的位置映射
+type sourceMap interface {
+	Source(start, end int) (int, int)
+}
+
+// sourceTracker 把边界文本定位回源文本
+type sourceTracker struct {
+	source    string
+	processed string
+	offsets   sourceMap
+	cursor    int
+}
+
+// locate 从上一个边界之后查找边界文本，填充源文本位置
+func (st *sourceTracker) locate(chunk *TTSChunk) {
+	if chunk.Text == "" {
+		return
+	}
+	i := strings.Index(st.processed[st.cursor:], chunk.Text)
+	if i < 0 {
+		return
+	}
+	start := st.cursor + i
+	end := start + len(chunk.Text)
+	st.cursor = end
+
+	srcStart, srcEnd := st.offsets.Source(start, end)
+	chunk.SourceStart = srcStart
+	chunk.SourceEnd = srcEnd
+	chunk.SourceText = st.source[srcStart:srcEnd]
+}
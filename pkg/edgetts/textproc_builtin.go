@@ -0,0 +1,179 @@
+package edgetts
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+	RegisterTextProcessor("whitespace", WhitespaceProcessor)
+	RegisterTextProcessor("url", URLProcessor)
+	RegisterTextProcessor("numbers-en", EnglishNumberProcessor)
+	for locale := range AbbreviationDictionaries {
+		RegisterTextProcessor("abbreviations-"+locale, NewAbbreviationProcessor(locale, nil))
+	}
+}
+
+// typographicReplacements 排版字符到普通字符的替换
+var typographicReplacements = map[string]string{
+	"“": `"`, "”": `"`, "„": `"`, "‘": "'", "’": "'",
+	"–": "-", "—": "-",
+	"\u00a0": " ", "\u200b": "", "\u200c": "", "\u200d": "", "\ufeff": "",
+}
+
+var whitespaceRegex = regexp.MustCompile(`[ \t\x{00a0}]{2,}|[“”„‘’–—\x{00a0}\x{200b}\x{200c}\x{200d}\x{feff}]`)
+
+// WhitespaceProcessor 合并连续空白，把排版引号、破折号等替换为普通字符，去掉零宽字符
+var WhitespaceProcessor = RegexpProcessor(whitespaceRegex, func(m []string) (string, bool) {
+	if r, ok := typographicReplacements[m[0]]; ok {
+		return r, true
+	}
+	return " ", true
+})
+
+var urlRegex = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+|\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+
+// urlWords URL 和邮箱中符号的读法
+var urlWords = map[rune]string{
+	'.': "dot", '/': "slash", '@': "at", '-': "dash", '_': "underscore",
+	':': "colon", '?': "question mark", '=': "equals", '&': "and", '#': "hash", '+': "plus", '%': "percent",
+}
+
+// URLProcessor 把 URL 和邮箱地址读作 "example dot com slash docs"，省略协议前缀
+var URLProcessor = RegexpProcessor(urlRegex, func(m []string) (string, bool) {
+	// 末尾的标点属于句子而不是 URL，原样保留
+	s := strings.TrimRight(m[0], ".,;:!?)")
+	return verbalizeURL(s) + m[0][len(s):], true
+})
+
+// verbalizeURL 把 URL 中的符号替换为单词
+func verbalizeURL(s string) string {
+	lower := strings.ToLower(s)
+	for _, prefix := range []string{"https://", "http://"} {
+		if strings.HasPrefix(lower, prefix) {
+			s = s[len(prefix):]
+			break
+		}
+	}
+	s = strings.TrimSuffix(s, "/")
+
+	var words []string
+	var word strings.Builder
+	for _, r := range s {
+		if w, ok := urlWords[r]; ok {
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			words = append(words, w)
+			continue
+		}
+		word.WriteRune(r)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return strings.Join(words, " ")
+}
+
+// AbbreviationDictionaries 各语言的缩写词典，修改后需要重新调用 NewAbbreviationProcessor 才会生效
+var AbbreviationDictionaries = map[string]map[string]string{
+	"en": {
+		"Mr.": "Mister", "Mrs.": "Missus", "Ms.": "Miz", "Dr.": "Doctor", "Prof.": "Professor",
+		"Sr.": "Senior", "Jr.": "Junior", "St.": "Saint", "Mt.": "Mount", "Ave.": "Avenue",
+		"etc.": "et cetera", "e.g.": "for example", "i.e.": "that is", "vs.": "versus",
+		"approx.": "approximately", "dept.": "department", "est.": "established",
+		"Jan.": "January", "Feb.": "February", "Mar.": "March", "Apr.": "April",
+		"Aug.": "August", "Sep.": "September", "Sept.": "September", "Oct.": "October",
+		"Nov.": "November", "Dec.": "December",
+		"km": "kilometers", "kg": "kilograms", "mph": "miles per hour",
+	},
+	"de": {
+		"z.B.": "zum Beispiel", "d.h.": "das heißt", "usw.": "und so weiter", "bzw.": "beziehungsweise",
+		"ca.": "circa", "Nr.": "Nummer", "Dr.": "Doktor", "Str.": "Straße", "evtl.": "eventuell",
+	},
+	"fr": {
+		"M.": "Monsieur", "Mme": "Madame", "Mlle": "Mademoiselle", "Dr": "Docteur",
+		"etc.": "et cetera", "p.ex.": "par exemple", "av.": "avenue",
+	},
+}
+
+// abbreviationsBeforeNames 通常出现在大写单词之前、不会结束句子的缩写
+var abbreviationsBeforeNames = map[string]bool{
+	"Mr.": true, "Mrs.": true, "Ms.": true, "Dr.": true, "Prof.": true, "St.": true, "Mt.": true,
+	"e.g.": true, "i.e.": true, "vs.": true,
+	"z.B.": true, "d.h.": true, "bzw.": true, "ca.": true, "Nr.": true,
+	"M.": true, "p.ex.": true, "av.": true,
+}
+
+// NewAbbreviationProcessor 按 locale 的缩写词典展开缩写，extra 中的条目会覆盖词典
+// locale 可以是 en、en-US 这样的形式，会依次尝试完整 locale 和语言部分
+// 以句点结尾的缩写位于输入末尾，或后面是空白和大写字母时（如 etc. The），展开后保留句点作为句子结束；
+// Mr.、e.g. 等通常位于大写单词之前的缩写除外
+func NewAbbreviationProcessor(locale string, extra map[string]string) TextProcessor {
+	dict := map[string]string{}
+	lang, _, _ := strings.Cut(locale, "-")
+	for _, key := range []string{lang, locale} {
+		for k, v := range AbbreviationDictionaries[key] {
+			dict[k] = v
+		}
+	}
+	for k, v := range extra {
+		dict[k] = v
+	}
+
+	keys := make([]string, 0, len(dict))
+	for k := range dict {
+		keys = append(keys, regexp.QuoteMeta(k))
+	}
+	if len(keys) == 0 {
+		return TextProcessorFunc(func(string) []Edit { return nil })
+	}
+	// 长的缩写优先匹配
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	re := regexp.MustCompile(strings.Join(keys, "|"))
+
+	return TextProcessorFunc(func(text string) []Edit {
+		var edits []Edit
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			// 要求缩写前后不是字母或数字，避免匹配单词的一部分
+			if r, _ := utf8.DecodeLastRuneInString(text[:loc[0]]); loc[0] > 0 && isWordRune(r) {
+				continue
+			}
+			if r, _ := utf8.DecodeRuneInString(text[loc[1]:]); loc[1] < len(text) && isWordRune(r) {
+				continue
+			}
+			abbr := text[loc[0]:loc[1]]
+			expanded := dict[abbr]
+			if strings.HasSuffix(abbr, ".") && !strings.HasSuffix(expanded, ".") && endsSentence(abbr, text[loc[1]:]) {
+				expanded += "."
+			}
+			edits = append(edits, Edit{Start: loc[0], End: loc[1], Text: expanded})
+		}
+		return edits
+	})
+}
+
+// endsSentence 判断缩写中的句点是否同时结束句子，rest 为缩写之后的文本
+func endsSentence(abbr, rest string) bool {
+	if abbreviationsBeforeNames[abbr] {
+		return false
+	}
+	next := strings.TrimLeftFunc(rest, unicode.IsSpace)
+	if next == "" {
+		return true
+	}
+	if len(next) == len(rest) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(next)
+	return unicode.IsUpper(r)
+}
+
+// isWordRune 判断字符是否是字母或数字
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
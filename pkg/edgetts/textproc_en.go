@@ -0,0 +1,207 @@
+package edgetts
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	smallNumbers = []string{
+		"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen",
+		"seventeen", "eighteen", "nineteen",
+	}
+	tens       = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	scaleWords = []string{"", "thousand", "million", "billion", "trillion"}
+	months     = []string{
+		"January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December",
+	}
+	// ordinalWords 以这些词结尾的基数词改为序数词时的特殊形式
+	ordinalWords = map[string]string{
+		"one": "first", "two": "second", "three": "third", "five": "fifth",
+		"eight": "eighth", "nine": "ninth", "twelve": "twelfth",
+	}
+	currencies = map[string][2]string{
+		"$": {"dollar", "cent"},
+		"€": {"euro", "cent"},
+		"£": {"pound", "penny"},
+	}
+)
+
+// englishNumberRegex 依次匹配日期、货币、序数、百分比和普通数字
+var englishNumberRegex = regexp.MustCompile(
+	`\b(?P<year>\d{4})-(?P<month>\d{2})-(?P<day>\d{2})\b` +
+		`|(?P<currency>[$€£])(?P<amount>\d{1,3}(?:,\d{3})+|\d+)(?:\.(?P<cents>\d{2}))?\b` +
+		`|\b(?P<ordinal>\d+)(?:st|nd|rd|th)\b` +
+		`|\b(?P<percent>\d+(?:\.\d+)?)%` +
+		`|\b(?P<number>\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:\.\d+)?)\b`)
+
+// EnglishNumberProcessor 把英文文本中的数字读出来
+// 包括普通数字、小数、序数（21st）、百分比、货币（$12.50）和 ISO 日期（2026-10-16）
+var EnglishNumberProcessor = RegexpProcessor(englishNumberRegex, func(m []string) (string, bool) {
+	group := func(name string) string {
+		return m[englishNumberRegex.SubexpIndex(name)]
+	}
+
+	switch {
+	case group("year") != "":
+		year, _ := strconv.Atoi(group("year"))
+		month, _ := strconv.Atoi(group("month"))
+		day, _ := strconv.Atoi(group("day"))
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return "", false
+		}
+		return months[month-1] + " " + OrdinalToWords(int64(day)) + ", " + YearToWords(year), true
+
+	case group("currency") != "":
+		names := currencies[group("currency")]
+		amount, err := strconv.ParseInt(strings.ReplaceAll(group("amount"), ",", ""), 10, 64)
+		if err != nil {
+			return "", false
+		}
+		s := NumberToWords(amount) + " " + plural(names[0], amount)
+		if cents, _ := strconv.ParseInt(group("cents"), 10, 64); cents > 0 {
+			s += " and " + NumberToWords(cents) + " " + plural(names[1], cents)
+		}
+		return s, true
+
+	case group("ordinal") != "":
+		n, err := strconv.ParseInt(group("ordinal"), 10, 64)
+		if err != nil {
+			return "", false
+		}
+		return OrdinalToWords(n), true
+
+	case group("percent") != "":
+		return decimalToWords(group("percent")) + " percent", true
+
+	case group("number") != "":
+		return decimalToWords(strings.ReplaceAll(group("number"), ",", "")), true
+	}
+	return "", false
+})
+
+// plural 返回单位的单复数形式
+func plural(unit string, n int64) string {
+	if n == 1 {
+		return unit
+	}
+	if unit == "penny" {
+		return "pence"
+	}
+	return unit + "s"
+}
+
+// decimalToWords 把 "12.05" 读作 "twelve point zero five"
+func decimalToWords(s string) string {
+	intPart, frac, _ := strings.Cut(s, ".")
+	n, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return s
+	}
+	words := NumberToWords(n)
+	if frac != "" {
+		words += " point " + digitsToWords(frac)
+	}
+	return words
+}
+
+// digitsToWords 逐位读出数字串，例如 "05" 读作 "zero five"
+func digitsToWords(digits string) string {
+	words := make([]string, 0, len(digits))
+	for _, d := range digits {
+		words = append(words, smallNumbers[d-'0'])
+	}
+	return strings.Join(words, " ")
+}
+
+// maxNumberWords NumberToWords 按数位读出的上限，超过 999 trillion 时逐位读出
+const maxNumberWords = 999_999_999_999_999
+
+// NumberToWords 把整数转换为英文，例如 1234 读作 "one thousand two hundred thirty-four"
+// 绝对值超过 999 trillion 时逐位读出，例如 "one two three ..."
+func NumberToWords(n int64) string {
+	if n == math.MinInt64 {
+		// -n 溢出，去掉负号后逐位读出
+		return "minus " + digitsToWords(strconv.FormatInt(n, 10)[1:])
+	}
+	if n < 0 {
+		return "minus " + NumberToWords(-n)
+	}
+	if n > maxNumberWords {
+		return digitsToWords(strconv.FormatInt(n, 10))
+	}
+	if n < 20 {
+		return smallNumbers[n]
+	}
+
+	var parts []string
+	for scale := 0; n > 0 && scale < len(scaleWords); scale++ {
+		group := n % 1000
+		n /= 1000
+		if group == 0 {
+			continue
+		}
+		words := hundredsToWords(group)
+		if scaleWords[scale] != "" {
+			words += " " + scaleWords[scale]
+		}
+		parts = append([]string{words}, parts...)
+	}
+	return strings.Join(parts, " ")
+}
+
+// hundredsToWords 把 1~999 转换为英文
+func hundredsToWords(n int64) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, smallNumbers[n/100]+" hundred")
+		n %= 100
+	}
+	switch {
+	case n == 0:
+	case n < 20:
+		parts = append(parts, smallNumbers[n])
+	case n%10 == 0:
+		parts = append(parts, tens[n/10])
+	default:
+		parts = append(parts, tens[n/10]+"-"+smallNumbers[n%10])
+	}
+	return strings.Join(parts, " ")
+}
+
+// OrdinalToWords 把整数转换为英文序数词，例如 21 读作 "twenty-first"
+func OrdinalToWords(n int64) string {
+	words := NumberToWords(n)
+	// 只替换最后一个单词
+	i := strings.LastIndexAny(words, " -") + 1
+	last := words[i:]
+	switch {
+	case ordinalWords[last] != "":
+		last = ordinalWords[last]
+	case strings.HasSuffix(last, "y"):
+		last = strings.TrimSuffix(last, "y") + "ieth"
+	default:
+		last += "th"
+	}
+	return words[:i] + last
+}
+
+// YearToWords 按年份的习惯读法转换，例如 1999 读作 "nineteen ninety-nine"，2026 读作 "twenty twenty-six"
+func YearToWords(year int) string {
+	if year < 1000 || year > 9999 || (year >= 2000 && year < 2010) || year%1000 == 0 {
+		return NumberToWords(int64(year))
+	}
+	hi, lo := year/100, year%100
+	switch {
+	case lo == 0:
+		return NumberToWords(int64(hi)) + " hundred"
+	case lo < 10:
+		return NumberToWords(int64(hi)) + " oh " + NumberToWords(int64(lo))
+	default:
+		return NumberToWords(int64(hi)) + " " + NumberToWords(int64(lo))
+	}
+}
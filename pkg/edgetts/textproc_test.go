@@ -0,0 +1,142 @@
+package edgetts_test
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+func TestBuiltinTextProcessors(t *testing.T) {
+	tests := []struct {
+		name string
+		proc edgetts.TextProcessor
+		in   string
+		want string
+	}{
+		{"whitespace", edgetts.WhitespaceProcessor, "a   “quoted”​ — text", `a "quoted" - text`},
+		{"url", edgetts.URLProcessor, "See https://example.com/docs.", "See example dot com slash docs."},
+		{"email", edgetts.URLProcessor, "Mail john.doe@example.org now", "Mail john dot doe at example dot org now"},
+		{"abbreviations", edgetts.NewAbbreviationProcessor("en-US", nil), "Dr. Smith vs. Mr. Jones, etc.", "Doctor Smith versus Mister Jones, et cetera."},
+		{"abbreviation ends sentence", edgetts.NewAbbreviationProcessor("en-US", nil), "Apples, pears, etc. Then e.g. Paris, etc., approx. 5", "Apples, pears, et cetera. Then for example Paris, et cetera, approximately 5"},
+		{"abbreviations extra", edgetts.NewAbbreviationProcessor("en", map[string]string{"ASAP": "as soon as possible"}), "Reply ASAP.", "Reply as soon as possible."},
+		{"abbreviations word boundary", edgetts.NewAbbreviationProcessor("en", nil), "Drs. Mrs.", "Drs. Missus"},
+		{"numbers", edgetts.EnglishNumberProcessor, "I have 1,234 apples and 3.05 pears", "I have one thousand two hundred thirty-four apples and three point zero five pears"},
+		{"large numbers", edgetts.EnglishNumberProcessor, "id 12345678901234567 end", "id one two three four five six seven eight nine zero one two three four five six seven end"},
+		{"ordinals", edgetts.EnglishNumberProcessor, "the 21st and 112th", "the twenty-first and one hundred twelfth"},
+		{"currency", edgetts.EnglishNumberProcessor, "costs $12.50 or £1", "costs twelve dollars and fifty cents or one pound"},
+		{"percent", edgetts.EnglishNumberProcessor, "up 7.5%", "up seven point five percent"},
+		{"date", edgetts.EnglishNumberProcessor, "on 2026-10-16", "on October sixteenth, twenty twenty-six"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := edgetts.ProcessText(tt.in, tt.proc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberWords(t *testing.T) {
+	for n, want := range map[int64]string{
+		0: "zero", 15: "fifteen", 40: "forty", 101: "one hundred one",
+		1_000_000: "one million", 2_000_017: "two million seventeen",
+		999_999_999_999_999: "nine hundred ninety-nine trillion nine hundred ninety-nine billion nine hundred ninety-nine million nine hundred ninety-nine thousand nine hundred ninety-nine",
+		// 超过 999 trillion 时逐位读出
+		1_000_000_000_000_000: "one zero zero zero zero zero zero zero zero zero zero zero zero zero zero zero",
+		-12:                   "minus twelve",
+		math.MinInt64:         "minus nine two two three three seven two zero three six eight five four seven seven five eight zero eight",
+	} {
+		if got := edgetts.NumberToWords(n); got != want {
+			t.Errorf("NumberToWords(%d) = %q, want %q", n, got, want)
+		}
+	}
+	for year, want := range map[int]string{
+		1999: "nineteen ninety-nine", 2000: "two thousand", 2005: "two thousand five",
+		1905: "nineteen oh five", 1900: "nineteen hundred",
+	} {
+		if got := edgetts.YearToWords(year); got != want {
+			t.Errorf("YearToWords(%d) = %q, want %q", year, got, want)
+		}
+	}
+}
+
+func TestProcessTextOffsetMap(t *testing.T) {
+	src := "Call Dr. Who at 10 or visit www.who.int"
+	out, m, err := edgetts.ProcessText(src,
+		edgetts.NewAbbreviationProcessor("en", nil),
+		edgetts.EnglishNumberProcessor,
+		edgetts.URLProcessor,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for word, want := range map[string]string{
+		"Doctor": "Dr.",
+		"Who":    "Who",
+		"ten":    "10",
+		"visit":  "visit",
+		"dot":    "www.who.int",
+	} {
+		i := strings.Index(out, word)
+		start, end := m.Source(i, i+len(word))
+		if got := src[start:end]; got != want {
+			t.Errorf("%q maps to %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestRegisterTextProcessor(t *testing.T) {
+	shout := edgetts.RegexpProcessor(regexp.MustCompile(`!+`), func(m []string) (string, bool) {
+		return "!", true
+	})
+	edgetts.RegisterTextProcessor("test-shout", shout)
+	p, ok := edgetts.LookupTextProcessor("test-shout")
+	if !ok {
+		t.Fatal("processor not registered")
+	}
+	if got, _, _ := edgetts.ProcessText("hey!!!", p); got != "hey!" {
+		t.Errorf("got %q", got)
+	}
+	for _, name := range []string{"whitespace", "url", "numbers-en", "abbreviations-en"} {
+		if _, ok := edgetts.LookupTextProcessor(name); !ok {
+			t.Errorf("builtin %q not registered", name)
+		}
+	}
+}
+
+func TestStreamTextProcessorsSourceText(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+
+	_, boundaries, err := synthesize(t, srv, "Dr. Smith paid $5 today",
+		edgetts.WithBoundary("WordBoundary"),
+		edgetts.WithTextProcessors(edgetts.NewAbbreviationProcessor("en", nil), edgetts.EnglishNumberProcessor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Requests()[0].Text; got != "Doctor Smith paid five dollars today" {
+		t.Fatalf("server text = %q", got)
+	}
+
+	want := map[string]string{"Doctor": "Dr.", "Smith": "Smith", "paid": "paid", "five": "$5", "dollars": "$5", "today": "today"}
+	for _, b := range boundaries {
+		if b.SourceText != want[b.Text] {
+			t.Errorf("boundary %q source = %q, want %q", b.Text, b.SourceText, want[b.Text])
+		}
+	}
+
+	// 未使用文本处理器时不设置源文本
+	_, boundaries, _ = synthesize(t, srv, "plain")
+	if boundaries[0].SourceText != "" {
+		t.Errorf("unexpected source text %q", boundaries[0].SourceText)
+	}
+}
@@ -0,0 +1,82 @@
+package edgetts
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName OpenTelemetry 中本库的 instrumentation 名称
+const tracerName = "github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+
+// WithTracerProvider 设置 OpenTelemetry TracerProvider，默认使用 otel.GetTracerProvider() 返回的全局实例
+// 每次 Stream 生成一个 edgetts.Stream span，每个文本块生成一个 edgetts.chunk 子 span，
+// 每次 WebSocket 握手生成一个 edgetts.dial 子 span；回合的 ssml.sent、turn.start、first_audio、
+// turn.end 和重试记录为 edgetts.chunk 上的事件
+func WithTracerProvider(tp trace.TracerProvider) CommunicateOption {
+	return func(c *Communicate) {
+		c.tracerProvider = tp
+	}
+}
+
+// Span 属性
+const (
+	attrVoice        = attribute.Key("edgetts.voice")
+	attrOutputFormat = attribute.Key("edgetts.output_format")
+	attrCharacters   = attribute.Key("edgetts.characters")
+	attrChunks       = attribute.Key("edgetts.chunks")
+	attrChunk        = attribute.Key("edgetts.chunk")
+	attrRequestID    = attribute.Key("edgetts.request_id")
+	attrConnectionID = attribute.Key("edgetts.connection_id")
+	attrAttempt      = attribute.Key("edgetts.attempt")
+	attrReconnect    = attribute.Key("edgetts.reconnect")
+	attrError        = attribute.Key("error.message")
+	attrSSMLBytes    = attribute.Key("edgetts.ssml_bytes")
+	attrAudioBytes   = attribute.Key("edgetts.audio_bytes")
+	attrStatusCode   = attribute.Key("http.response.status_code")
+)
+
+// tracerFrom 返回创建 span 的 Tracer，tp 为 nil 时使用全局 TracerProvider
+func tracerFrom(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// startStreamSpan 开始一次合成的根 span
+func (c *Communicate) startStreamSpan(ctx context.Context) (context.Context, trace.Span) {
+	voice := c.ttsConfig.Voice
+	if len(c.speakers) > 0 {
+		voice = "" // 对话的每个句子可能使用不同的语音
+	}
+	return c.tracer.Start(ctx, "edgetts.Stream", trace.WithAttributes(
+		attrVoice.String(voice),
+		attrOutputFormat.String(string(c.ttsConfig.Format)),
+		attrCharacters.Int(c.characters),
+		attrChunks.Int(len(c.texts)),
+	))
+}
+
+// endSpan 记录错误和状态后结束 span
+func endSpan(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// retried 记录一次重试：写入当前 span 的 retry 事件并调用 OnRetry 回调
+func (c *Communicate) retried(ctx context.Context, r RetryInfo) {
+	trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+		attrAttempt.Int(r.Attempt),
+		attrReconnect.Bool(r.Reconnect),
+		attrError.String(r.Err.Error()),
+	))
+	c.hooks.OnRetry(r)
+}
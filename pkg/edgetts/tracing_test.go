@@ -0,0 +1,177 @@
+package edgetts_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+// newTracer 返回把 span 同步写入内存的 TracerProvider
+func newTracer(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	return tp, exp
+}
+
+// spanNamed 返回第 n 个名为 name 的 span
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string, n int) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name == name {
+			if n == 0 {
+				return s
+			}
+			n--
+		}
+	}
+	t.Fatalf("span %s #%d not found", name, n)
+	return tracetest.SpanStub{}
+}
+
+func attr(s tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func events(s tracetest.SpanStub) string {
+	var names []string
+	for _, e := range s.Events {
+		names = append(names, e.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestTracingSpans(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithForbiddenHandshakes(1))
+	defer srv.Close()
+	tp, exp := newTracer(t)
+
+	// 调用方的 span 作为根 span 的父 span
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx, parent := tp.Tracer("test").Start(ctx, "request")
+	comm, err := edgetts.NewCommunicate("One. Two.", "",
+		edgetts.WithClient(srv.Client()),
+		edgetts.WithChunkSize(5),
+		edgetts.WithRetry(fastRetry),
+		edgetts.WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := comm.StreamSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+	audioBytes := 0
+	for _, c := range chunks {
+		audioBytes += len(c.Data)
+	}
+
+	spans := exp.GetSpans()
+	root := spanNamed(t, spans, "edgetts.Stream", 0)
+	if root.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("root parent = %s, want %s", root.Parent.SpanID(), parent.SpanContext().SpanID())
+	}
+	if got := attr(root, "edgetts.voice").AsString(); !strings.Contains(got, "EmmaMultilingualNeural") {
+		t.Errorf("voice = %q", got)
+	}
+	if got := attr(root, "edgetts.characters").AsInt64(); got != 9 {
+		t.Errorf("characters = %d, want 9", got)
+	}
+	if got := attr(root, "edgetts.chunks").AsInt64(); got != 2 {
+		t.Errorf("chunks = %d, want 2", got)
+	}
+	if got := attr(root, "edgetts.audio_bytes").AsInt64(); got != int64(audioBytes) || got == 0 {
+		t.Errorf("audio_bytes = %d, want %d", got, audioBytes)
+	}
+	if root.Status.Code == codes.Error {
+		t.Errorf("root status = %v", root.Status)
+	}
+
+	reqs := srv.Requests()
+	for i := 0; i < 2; i++ {
+		chunk := spanNamed(t, spans, "edgetts.chunk", i)
+		if chunk.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("chunk %d is not a child of the root span", i)
+		}
+		if got := attr(chunk, "edgetts.request_id").AsString(); got != reqs[i].RequestID {
+			t.Errorf("chunk %d request_id = %q, want %q", i, got, reqs[i].RequestID)
+		}
+		want := "ssml.sent turn.start first_audio turn.end"
+		if i == 0 {
+			want = "retry " + want
+		}
+		if got := events(chunk); got != want {
+			t.Errorf("chunk %d events = %q, want %q", i, got, want)
+		}
+	}
+
+	// 第一次握手返回 403，两次握手都在第一个文本块的 span 下
+	first := spanNamed(t, spans, "edgetts.chunk", 0)
+	for i, code := range []int64{403, 101} {
+		dial := spanNamed(t, spans, "edgetts.dial", i)
+		if dial.Parent.SpanID() != first.SpanContext.SpanID() {
+			t.Errorf("dial %d is not a child of the first chunk span", i)
+		}
+		if got := attr(dial, "http.response.status_code").AsInt64(); got != code {
+			t.Errorf("dial %d status = %d, want %d", i, got, code)
+		}
+	}
+	if dial := spanNamed(t, spans, "edgetts.dial", 0); dial.Status.Code != codes.Error || events(dial) != "exception" {
+		t.Errorf("failed dial status = %v, events = %q", dial.Status, events(dial))
+	}
+}
+
+func TestTracingError(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithFault(edgettstest.FaultNoAudio))
+	defer srv.Close()
+	tp, exp := newTracer(t)
+
+	if _, _, err := synthesize(t, srv, "hello", edgetts.WithTracerProvider(tp)); err == nil {
+		t.Fatal("expected error")
+	}
+
+	spans := exp.GetSpans()
+	for _, name := range []string{"edgetts.chunk", "edgetts.Stream"} {
+		s := spanNamed(t, spans, name, 0)
+		if s.Status.Code != codes.Error || !strings.Contains(events(s), "exception") {
+			t.Errorf("%s status = %v, events = %q", name, s.Status, events(s))
+		}
+	}
+}
+
+func TestTracingSSMLCharacters(t *testing.T) {
+	srv := edgettstest.NewServer()
+	defer srv.Close()
+	tp, exp := newTracer(t)
+
+	ssml := "<speak version='1.0' xml:lang='en-US'>\n  <p>Tom &amp; Jerry</p>\n  <break time='200ms'/>\n  <emphasis>bye</emphasis>\n</speak>"
+	comm, err := edgetts.NewCommunicateSSML(ssml, "", edgetts.WithClient(srv.Client()), edgetts.WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comm.StreamSync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只计朗读文本 "Tom & Jerry" 和 "bye"，不计标记和缩进
+	root := spanNamed(t, exp.GetSpans(), "edgetts.Stream", 0)
+	if got := attr(root, "edgetts.characters").AsInt64(); got != 14 {
+		t.Errorf("characters = %d, want 14", got)
+	}
+}
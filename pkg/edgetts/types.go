@@ -7,6 +7,12 @@ type TTSChunk struct {
 	Duration float64 // 仅用于 WordBoundary 和 SentenceBoundary
 	Offset   float64 // 仅用于 WordBoundary 和 SentenceBoundary
 	Text     string  // 仅用于 WordBoundary 和 SentenceBoundary
+	Speaker  string  // 说话人，仅用于对话中的 WordBoundary 和 SentenceBoundary
+
+	// 边界在处理前文本中的位置，仅在使用文本处理器时设置；SourceText 为空表示无法定位
+	SourceStart int
+	SourceEnd   int
+	SourceText  string
 }
 
 // VoiceTag 语音标签
@@ -25,7 +31,9 @@ type Voice struct {
 	FriendlyName   string   `json:"FriendlyName"`
 	Status         string   `json:"Status"`
 	VoiceTag       VoiceTag `json:"VoiceTag"`
-	Language       string   `json:"Language,omitempty"` // VoicesManager 添加的字段
+	StyleList      []string `json:"StyleList,omitempty"`    // 支持的说话风格，服务端的语音列表不一定提供
+	RolePlayList   []string `json:"RolePlayList,omitempty"` // 支持的角色扮演，服务端的语音列表不一定提供
+	Language       string   `json:"Language,omitempty"`     // VoicesManager 添加的字段
 }
 
 // TTSConfig TTS 配置
@@ -35,6 +43,11 @@ type TTSConfig struct {
 	Volume   string
 	Pitch    string
 	Boundary string // "WordBoundary" 或 "SentenceBoundary"
+	Format   OutputFormat
+
+	Style       string  // 说话风格，例如 cheerful
+	StyleDegree float64 // 说话风格强度，0 表示默认
+	Role        string  // 角色扮演，例如 Girl
 }
 
 // CommunicateState 通信状态
@@ -115,15 +115,31 @@ func SplitTextByByteLength(text string, byteLength int) [][]byte {
 	return result
 }
 
+// speakOpenTag SSML 根元素的开始标签
+const speakOpenTag = "<speak version='1.0' xmlns='http://www.w3.org/2001/10/synthesis' xml:lang='en-US'>"
+
+// speakOpenTagMSTTS 声明 mstts 命名空间的根元素开始标签
+const speakOpenTagMSTTS = "<speak version='1.0' xmlns='http://www.w3.org/2001/10/synthesis' xmlns:mstts='https://www.w3.org/2001/mstts' xml:lang='en-US'>"
+
 // MKSSML 创建 SSML 字符串
 func MKSSML(tc *TTSConfig, escapedText string) string {
-	return "<speak version='1.0' xmlns='http://www.w3.org/2001/10/synthesis' xml:lang='en-US'>" +
-		"<voice name='" + tc.Voice + "'>" +
-		"<prosody pitch='" + tc.Pitch + "' rate='" + tc.Rate + "' volume='" + tc.Volume + "'>" +
-		escapedText +
-		"</prosody>" +
-		"</voice>" +
-		"</speak>"
+	if tc.hasStyle() {
+		return speakOpenTagMSTTS + voiceProsody(tc, escapedText) + "</speak>"
+	}
+	return speakOpenTag + voiceProsody(tc, escapedText) + "</speak>"
+}
+
+// voiceProsody 用 voice 和 prosody 元素包装内容，设置了说话风格时加上 mstts:express-as
+func voiceProsody(tc *TTSConfig, content string) string {
+	prosody := "<prosody pitch='" + tc.Pitch + "' rate='" + tc.Rate + "' volume='" + tc.Volume + "'>" +
+		content +
+		"</prosody>"
+	if tc.hasStyle() {
+		prosody = expressAs(tc, prosody)
+	}
+	return "<voice name='" + tc.Voice + "'>" +
+		prosody +
+		"</voice>"
 }
 
 // SSMLHeadersPlusData 返回请求的 headers 和 data
@@ -135,24 +151,30 @@ func SSMLHeadersPlusData(requestID, timestamp, ssml string) string {
 		ssml
 }
 
-// ValidateTTSConfig 验证 TTS 配置
-func ValidateTTSConfig(tc *TTSConfig) error {
-	// 验证并转换 voice 格式
+// fullVoiceName 把 zh-CN-XiaoxiaoNeural 这样的短名称转换为服务端使用的完整名称，其他格式原样返回
+func fullVoiceName(voice string) string {
 	voicePattern := regexp.MustCompile(`^([a-z]{2,})-([A-Z]{2,})-(.+Neural)$`)
-	matches := voicePattern.FindStringSubmatch(tc.Voice)
-	if matches != nil {
-		lang := matches[1]
-		region := matches[2]
-		name := matches[3]
-
-		if idx := strings.Index(name, "-"); idx != -1 {
-			region = region + "-" + name[:idx]
-			name = name[idx+1:]
-		}
+	matches := voicePattern.FindStringSubmatch(voice)
+	if matches == nil {
+		return voice
+	}
+	lang := matches[1]
+	region := matches[2]
+	name := matches[3]
 
-		tc.Voice = "Microsoft Server Speech Text to Speech Voice (" + lang + "-" + region + ", " + name + ")"
+	if idx := strings.Index(name, "-"); idx != -1 {
+		region = region + "-" + name[:idx]
+		name = name[idx+1:]
 	}
 
+	return "Microsoft Server Speech Text to Speech Voice (" + lang + "-" + region + ", " + name + ")"
+}
+
+// ValidateTTSConfig 验证 TTS 配置
+func ValidateTTSConfig(tc *TTSConfig) error {
+	// 验证并转换 voice 格式
+	tc.Voice = fullVoiceName(tc.Voice)
+
 	// 验证 voice 格式
 	voiceFullPattern := regexp.MustCompile(`^Microsoft Server Speech Text to Speech Voice \(.+,.+\)$`)
 	if !voiceFullPattern.MatchString(tc.Voice) {
@@ -177,6 +199,19 @@ func ValidateTTSConfig(tc *TTSConfig) error {
 		return ErrInvalidPitch
 	}
 
+	// 验证说话风格和角色
+	if err := validateStyle(tc); err != nil {
+		return err
+	}
+
+	// 验证输出格式，未设置时使用默认格式
+	if tc.Format == "" {
+		tc.Format = DefaultOutputFormat
+	}
+	if !tc.Format.Valid() {
+		return ErrInvalidOutputFormat
+	}
+
 	return nil
 }
 
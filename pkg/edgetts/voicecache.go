@@ -0,0 +1,145 @@
+package edgetts
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//go:generate go run gen_voices_snapshot.go
+
+// DefaultVoiceCacheTTL 语音列表磁盘缓存的默认有效期
+const DefaultVoiceCacheTTL = 24 * time.Hour
+
+// VoiceSource 语音列表的来源
+type VoiceSource string
+
+const (
+	// VoiceSourceCustom 调用方传入的语音列表
+	VoiceSourceCustom VoiceSource = "custom"
+	// VoiceSourceFile VoicesFile 指定的文件
+	VoiceSourceFile VoiceSource = "file"
+	// VoiceSourceNetwork 从服务端获取
+	VoiceSourceNetwork VoiceSource = "network"
+	// VoiceSourceCache 磁盘缓存，包括服务端返回 304 后继续使用的缓存
+	VoiceSourceCache VoiceSource = "cache"
+	// VoiceSourceSnapshot 编译进程序的快照
+	VoiceSourceSnapshot VoiceSource = "snapshot"
+)
+
+// VoiceCache 语音列表的磁盘缓存
+// 有效期内直接使用缓存；过期后带 ETag/If-Modified-Since 向服务端确认，联网失败时继续使用过期的缓存
+type VoiceCache struct {
+	// Path 缓存文件路径，默认为 DefaultVoiceCachePath()
+	Path string
+	// TTL 有效期，默认为 DefaultVoiceCacheTTL
+	TTL time.Duration
+}
+
+// DefaultVoiceCachePath 返回默认的缓存文件路径：$XDG_CACHE_HOME/edge-tts/voices.json，
+// 未设置 XDG_CACHE_HOME 时为 ~/.cache/edge-tts/voices.json（macOS 和 Windows 使用系统的缓存目录）
+func DefaultVoiceCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "edge-tts", "voices.json"), nil
+}
+
+func (vc *VoiceCache) path() (string, error) {
+	if vc.Path != "" {
+		return vc.Path, nil
+	}
+	return DefaultVoiceCachePath()
+}
+
+func (vc *VoiceCache) ttl() time.Duration {
+	if vc.TTL <= 0 {
+		return DefaultVoiceCacheTTL
+	}
+	return vc.TTL
+}
+
+// read 读取缓存，文件不存在时返回 nil
+func (vc *VoiceCache) read() (*voiceList, error) {
+	path, err := vc.path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list voiceList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid voice cache %s: %w", path, err)
+	}
+	if len(list.Voices) == 0 {
+		return nil, nil
+	}
+	list.Voices = normalizeVoices(list.Voices)
+	return &list, nil
+}
+
+// write 原子地写入缓存
+func (vc *VoiceCache) write(list *voiceList) error {
+	path, err := vc.path()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".voices-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+//go:embed voices_snapshot.json
+var voicesSnapshot []byte
+
+// SnapshotVoices 返回编译进程序的语音列表快照，联网失败时作为最后的备选
+// 仓库中的快照是手工整理的部分列表（约 100 个常用语音，偏重中文、英文和主要欧洲语言），不是服务端的完整列表；
+// 不在快照中的语音仍然可以用于合成。在能联网的环境中运行 go generate 可替换为服务端的完整列表
+func SnapshotVoices() []Voice {
+	var voices []Voice
+	if err := json.Unmarshal(voicesSnapshot, &voices); err != nil {
+		panic("edgetts: invalid voice snapshot: " + err.Error())
+	}
+	return normalizeVoices(voices)
+}
+
+// LoadVoicesFile 读取 JSON 格式的语音列表文件，格式与 ListVoices 返回的相同
+func LoadVoicesFile(path string) ([]Voice, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var voices []Voice
+	if err := json.Unmarshal(data, &voices); err != nil {
+		return nil, fmt.Errorf("invalid voices file %s: %w", path, err)
+	}
+	return normalizeVoices(voices), nil
+}
@@ -0,0 +1,131 @@
+package edgetts_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts/edgettstest"
+)
+
+// newManager 返回从 srv 获取语音列表、不重试的 VoicesManager
+func newManager(srv *edgettstest.Server, cache *edgetts.VoiceCache) *edgetts.VoicesManager {
+	vm := edgetts.NewVoicesManager()
+	vm.ListOptions = &edgetts.ListVoicesOptions{Client: srv.Client(), Retry: &edgetts.NoRetry}
+	vm.Cache = cache
+	return vm
+}
+
+func TestVoicesManagerDiskCache(t *testing.T) {
+	srv := edgettstest.NewServer(edgettstest.WithVoiceListETag(`"v1"`))
+	defer srv.Close()
+	cache := &edgetts.VoiceCache{Path: filepath.Join(t.TempDir(), "edge-tts", "voices.json"), TTL: time.Hour}
+	ctx := context.Background()
+
+	create := func(want edgetts.VoiceSource) {
+		t.Helper()
+		vm := newManager(srv, cache)
+		if err := vm.Create(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+		if vm.Source != want || len(vm.Voices) != len(edgettstest.DefaultVoices()) || vm.UpdatedAt.IsZero() {
+			t.Errorf("source = %s, voices = %d, updated = %v; want %s", vm.Source, len(vm.Voices), vm.UpdatedAt, want)
+		}
+		if vm.Voices[0].Language == "" {
+			t.Error("Language not set")
+		}
+	}
+
+	create(edgetts.VoiceSourceNetwork)
+	if _, err := os.Stat(cache.Path); err != nil {
+		t.Fatalf("cache not written: %v", err)
+	}
+
+	// 有效期内不联网
+	create(edgetts.VoiceSourceCache)
+	if srv.Handshakes() != 1 {
+		t.Errorf("requests = %d, want 1", srv.Handshakes())
+	}
+
+	// 过期后带 ETag 确认，服务端返回 304
+	cache.TTL = time.Nanosecond
+	create(edgetts.VoiceSourceCache)
+	if srv.Handshakes() != 2 || srv.NotModified() != 1 {
+		t.Errorf("requests = %d, not modified = %d; want 2 and 1", srv.Handshakes(), srv.NotModified())
+	}
+}
+
+func TestVoicesManagerOffline(t *testing.T) {
+	srv := edgettstest.NewServer()
+	cache := &edgetts.VoiceCache{Path: filepath.Join(t.TempDir(), "voices.json"), TTL: time.Nanosecond}
+	ctx := context.Background()
+
+	if err := newManager(srv, cache).Create(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	// 联网失败时使用过期的缓存
+	vm := newManager(srv, cache)
+	if err := vm.Create(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if vm.Source != edgetts.VoiceSourceCache || len(vm.Voices) != len(edgettstest.DefaultVoices()) {
+		t.Errorf("source = %s, voices = %d", vm.Source, len(vm.Voices))
+	}
+
+	// 没有缓存时使用快照，未开启快照时返回错误
+	vm = newManager(srv, nil)
+	if err := vm.Create(ctx, nil); err == nil {
+		t.Fatal("expected error without snapshot fallback")
+	}
+	vm.SnapshotFallback = true
+	if err := vm.Create(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if vm.Source != edgetts.VoiceSourceSnapshot || len(vm.Voices) != len(edgetts.SnapshotVoices()) {
+		t.Errorf("source = %s, voices = %d", vm.Source, len(vm.Voices))
+	}
+}
+
+func TestVoicesManagerVoicesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "voices.json")
+	data, _ := json.Marshal(edgettstest.DefaultVoices()[:2])
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := edgetts.NewVoicesManager()
+	vm.VoicesFile = path
+	if err := vm.Create(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if vm.Source != edgetts.VoiceSourceFile || len(vm.Voices) != 2 {
+		t.Errorf("source = %s, voices = %d", vm.Source, len(vm.Voices))
+	}
+
+	os.WriteFile(path, []byte("not json"), 0o644)
+	if err := vm.Create(context.Background(), nil); err == nil {
+		t.Error("expected error for invalid voices file")
+	}
+}
+
+func TestSnapshotVoices(t *testing.T) {
+	voices := edgetts.SnapshotVoices()
+	found := false
+	for _, v := range voices {
+		if v.ShortName == edgetts.DefaultVoice {
+			found = true
+		}
+		if v.Name == "" || v.Locale == "" || v.Gender == "" || v.VoiceTag.ContentCategories == nil {
+			t.Errorf("incomplete voice %+v", v)
+		}
+	}
+	if !found {
+		t.Errorf("snapshot does not contain the default voice %s", edgetts.DefaultVoice)
+	}
+}
@@ -0,0 +1,364 @@
+package edgetts
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// KnownContentCategories 已知的内容类别（VoiceTag.ContentCategories）
+var KnownContentCategories = []string{
+	"Cartoon", "Conversation", "Copilot", "Dialect", "General", "News", "Novel", "Sports",
+}
+
+// KnownVoicePersonalities 已知的声音特点（VoiceTag.VoicePersonalities）
+var KnownVoicePersonalities = []string{
+	"Approachable", "Authentic", "Authority", "Bright", "Caring", "Casual", "Cheerful",
+	"Clear", "Comfort", "Confident", "Considerate", "Conversational", "Cute", "Expressive",
+	"Friendly", "Honest", "Humorous", "Lively", "Passion", "Pleasant", "Positive",
+	"Professional", "Rational", "Reliable", "Sincere", "Sunshine", "Warm",
+}
+
+// VoiceQuery 语音查询条件，空字段不限制，字符串比较不区分大小写
+// 没有符合全部条件的语音时先放宽 Categories 和 Personalities，再放宽 Locale，见 Query
+type VoiceQuery struct {
+	// Name 名称模糊匹配，匹配 ShortName、FriendlyName 和去掉地区与 Neural 后缀的名称（如 xiaoxiao）
+	// 多个词以空格分隔时每个词都要匹配
+	Name string
+	// Keywords 只影响排序的词，与名称或标签匹配的语音排在前面，例如描述中无法识别的 calm、young
+	Keywords []string
+	// Gender Male 或 Female
+	Gender string
+	// Locale 地区，例如 zh-HK；没有符合条件的语音时依次放宽为同一语言（zh）和任意地区
+	Locale string
+	// Categories 要求 VoiceTag.ContentCategories 全部包含，放宽后包含的越多排序越靠前
+	Categories []string
+	// Personalities 要求 VoiceTag.VoicePersonalities 全部包含，放宽后包含的越多排序越靠前
+	Personalities []string
+	// Multilingual 只查找多语言语音（名称包含 Multilingual）
+	Multilingual bool
+	// Status 语音状态，例如 GA、Preview
+	Status string
+}
+
+// IsMultilingual 判断语音是否为多语言语音
+func (v Voice) IsMultilingual() bool {
+	return strings.Contains(v.ShortName, "Multilingual")
+}
+
+// Query 查找符合条件的语音，按匹配程度（名称、Keywords 和放宽后的标签）排序，相同时按 ShortName 排序
+// 没有结果时按以下顺序放宽，返回第一个有结果的范围：
+// 在 Locale 内去掉 Categories 和 Personalities 的限制，再依次放宽为同一语言和任意地区（每一级同样先要求标签）
+// Name、Gender、Multilingual 和 Status 始终是必须满足的条件
+func (vm *VoicesManager) Query(q VoiceQuery) ([]Voice, error) {
+	if !vm.CalledCreate {
+		return nil, fmt.Errorf("VoicesManager.Query() called before VoicesManager.Create()")
+	}
+	ranked := vm.rank(q)
+	voices := make([]Voice, len(ranked))
+	for i, r := range ranked {
+		voices[i] = r.voice
+	}
+	return voices, nil
+}
+
+// Pick 从匹配程度最高的语音中按 seed 确定性地选出一个，相同的语音列表、条件和 seed 总是得到同一个语音
+// 没有符合条件的语音时返回 ErrNoVoiceMatch
+func (vm *VoicesManager) Pick(q VoiceQuery, seed int64) (Voice, error) {
+	if !vm.CalledCreate {
+		return Voice{}, fmt.Errorf("VoicesManager.Pick() called before VoicesManager.Create()")
+	}
+	ranked := vm.rank(q)
+	if len(ranked) == 0 {
+		return Voice{}, ErrNoVoiceMatch
+	}
+	best := 1
+	for best < len(ranked) && ranked[best].score == ranked[0].score {
+		best++
+	}
+	return ranked[rand.New(rand.NewSource(seed)).Intn(best)].voice, nil
+}
+
+// rankedVoice 带匹配分数的语音
+type rankedVoice struct {
+	voice Voice
+	score int
+}
+
+// rank 按放宽顺序过滤语音并排序
+func (vm *VoicesManager) rank(q VoiceQuery) []rankedVoice {
+	terms := strings.Fields(strings.ToLower(q.Name))
+	var keywords []string
+	for _, k := range q.Keywords {
+		keywords = append(keywords, strings.Fields(strings.ToLower(k))...)
+	}
+	hasTags := len(q.Categories) > 0 || len(q.Personalities) > 0
+
+	for _, locale := range localeFallbacks(q.Locale) {
+		for _, strictTags := range []bool{true, false} {
+			if !strictTags && !hasTags {
+				continue
+			}
+			if ranked := q.rankLevel(vm.Voices, locale, strictTags, terms, keywords); len(ranked) > 0 {
+				return ranked
+			}
+		}
+	}
+	return nil
+}
+
+// rankLevel 在一个放宽范围内过滤语音并排序，strictTags 为 false 时标签只参与排序
+func (q VoiceQuery) rankLevel(voices []Voice, locale string, strictTags bool, terms, keywords []string) []rankedVoice {
+	var ranked []rankedVoice
+	for _, v := range voices {
+		if !q.matches(v, locale, strictTags) {
+			continue
+		}
+		score, ok := nameScore(v, terms)
+		if !ok {
+			continue
+		}
+		score += keywordScore(v, keywords)
+		if !strictTags {
+			score += countFold(v.VoiceTag.ContentCategories, q.Categories) +
+				countFold(v.VoiceTag.VoicePersonalities, q.Personalities)
+		}
+		ranked = append(ranked, rankedVoice{voice: v, score: score})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].voice.ShortName < ranked[j].voice.ShortName
+	})
+	return ranked
+}
+
+// localeFallbacks 返回 Locale 的回退顺序，例如 zh-HK、zh、任意地区（空字符串）
+func localeFallbacks(locale string) []string {
+	if locale == "" {
+		return []string{""}
+	}
+	chain := []string{locale}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		chain = append(chain, lang)
+	}
+	return append(chain, "")
+}
+
+// matches 判断语音是否符合名称以外的条件，locale 为不含地区的语言代码时按语言比较
+// strictTags 为 false 时不检查 Categories 和 Personalities
+func (q VoiceQuery) matches(v Voice, locale string, strictTags bool) bool {
+	if q.Gender != "" && !strings.EqualFold(v.Gender, q.Gender) {
+		return false
+	}
+	if q.Status != "" && !strings.EqualFold(v.Status, q.Status) {
+		return false
+	}
+	if q.Multilingual && !v.IsMultilingual() {
+		return false
+	}
+	if locale != "" && !strings.EqualFold(v.Locale, locale) {
+		lang, _, _ := strings.Cut(v.Locale, "-")
+		if strings.Contains(locale, "-") || !strings.EqualFold(lang, locale) {
+			return false
+		}
+	}
+	if !strictTags {
+		return true
+	}
+	return countFold(v.VoiceTag.ContentCategories, q.Categories) == len(q.Categories) &&
+		countFold(v.VoiceTag.VoicePersonalities, q.Personalities) == len(q.Personalities)
+}
+
+// countFold 返回 want 中有多少个值出现在 have 中，不区分大小写
+func countFold(have, want []string) int {
+	n := 0
+	for _, w := range want {
+		if slices.ContainsFunc(have, func(h string) bool { return strings.EqualFold(h, w) }) {
+			n++
+		}
+	}
+	return n
+}
+
+// keywordScore 返回与名称或标签匹配的关键词的分数之和，不匹配的关键词不影响结果
+func keywordScore(v Voice, keywords []string) int {
+	total := 0
+	for _, k := range keywords {
+		if score, ok := nameScore(v, []string{k}); ok {
+			total += score
+		} else if countFold(v.VoiceTag.ContentCategories, []string{k})+countFold(v.VoiceTag.VoicePersonalities, []string{k}) > 0 {
+			total++
+		}
+	}
+	return total
+}
+
+// 名称匹配分数：完全相同 > 前缀 > 子串 > 模糊（按顺序包含所有字母或拼写相近）
+const (
+	nameFuzzy = iota + 1
+	nameSubstring
+	namePrefix
+	nameExact
+)
+
+// nameScore 返回所有词的匹配分数之和，有词不匹配时返回 false
+func nameScore(v Voice, terms []string) (int, bool) {
+	short := strings.ToLower(v.ShortName)
+	friendly := strings.ToLower(v.FriendlyName)
+	name := voiceBaseName(v.ShortName)
+	total := 0
+	for _, t := range terms {
+		score := 0
+		switch {
+		case t == short || t == name:
+			score = nameExact
+		case strings.HasPrefix(name, t):
+			score = namePrefix
+		case strings.Contains(short, t) || strings.Contains(friendly, t):
+			score = nameSubstring
+		case isSubsequence(t, name) || levenshtein(t, name) <= max(1, len(t)/4):
+			score = nameFuzzy
+		default:
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+// voiceBaseName 返回去掉地区和 Neural 后缀的小写名称，例如 zh-CN-XiaoxiaoNeural 为 xiaoxiao
+func voiceBaseName(shortName string) string {
+	name := shortName
+	if i := strings.LastIndex(name, "-"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(strings.TrimSuffix(name, "Neural"))
+}
+
+// isSubsequence 判断 s 的字母是否按顺序出现在 t 中
+func isSubsequence(s, t string) bool {
+	i := 0
+	for j := 0; i < len(s) && j < len(t); j++ {
+		if s[i] == t[j] {
+			i++
+		}
+	}
+	return i == len(s)
+}
+
+// levenshtein 返回两个字符串的编辑距离
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// descriptionWords 描述中的性别、地区、语言和用途词
+var descriptionWords = map[string]VoiceQuery{
+	"female": {Gender: "Female"}, "woman": {Gender: "Female"}, "girl": {Gender: "Female"},
+	"male": {Gender: "Male"}, "man": {Gender: "Male"}, "boy": {Gender: "Male"},
+
+	"american": {Locale: "en-US"}, "british": {Locale: "en-GB"}, "uk": {Locale: "en-GB"},
+	"australian": {Locale: "en-AU"}, "canadian": {Locale: "en-CA"}, "indian": {Locale: "en-IN"},
+	"irish": {Locale: "en-IE"}, "english": {Locale: "en"},
+	"chinese": {Locale: "zh"}, "mandarin": {Locale: "zh-CN"}, "cantonese": {Locale: "zh-HK"},
+	"taiwanese": {Locale: "zh-TW"}, "japanese": {Locale: "ja"}, "korean": {Locale: "ko"},
+	"french": {Locale: "fr"}, "german": {Locale: "de"}, "spanish": {Locale: "es"},
+	"mexican": {Locale: "es-MX"}, "italian": {Locale: "it"}, "portuguese": {Locale: "pt"},
+	"brazilian": {Locale: "pt-BR"}, "russian": {Locale: "ru"},
+
+	"narrator": {Categories: []string{"Novel"}}, "audiobook": {Categories: []string{"Novel"}},
+	"storyteller": {Categories: []string{"Novel"}}, "newscaster": {Categories: []string{"News"}},
+
+	"multilingual": {Multilingual: true},
+	"ga":           {Status: "GA"}, "preview": {Status: "Preview"},
+}
+
+// descriptionStopWords 描述中忽略的词
+var descriptionStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "with": true, "voice": true, "speaker": true, "accent": true,
+}
+
+// localePattern 描述中直接写出的地区，例如 zh-HK
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}-[a-z]{2,4}$`)
+
+// ParseVoiceQuery 把 "cheerful female British" 这样的描述解析为查询条件，不区分大小写：
+// 性别词（female、man 等）、地区和语言形容词（British、Cantonese 等）或地区代码（zh-HK）、
+// 用途词（narrator 等）、multilingual、状态（GA、Preview）、KnownContentCategories 和 KnownVoicePersonalities 中的值，
+// 其余的词（如 calm、young 或语音名称）作为只影响排序的 Keywords，不会导致没有结果
+func ParseVoiceQuery(description string) VoiceQuery {
+	var q VoiceQuery
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';' || r == '\t' || r == '\n'
+	})
+	for _, w := range words {
+		if descriptionStopWords[w] {
+			continue
+		}
+		if d, ok := descriptionWords[w]; ok {
+			q = q.merge(d)
+			continue
+		}
+		if localePattern.MatchString(w) {
+			lang, region, _ := strings.Cut(w, "-")
+			q.Locale = lang + "-" + strings.ToUpper(region)
+			continue
+		}
+		if c, ok := lookupFold(KnownContentCategories, w); ok {
+			q.Categories = append(q.Categories, c)
+			continue
+		}
+		if p, ok := lookupFold(KnownVoicePersonalities, w); ok {
+			q.Personalities = append(q.Personalities, p)
+			continue
+		}
+		q.Keywords = append(q.Keywords, w)
+	}
+	return q
+}
+
+// merge 用 d 中的非空字段覆盖 q，Categories 和 Personalities 追加到 q
+func (q VoiceQuery) merge(d VoiceQuery) VoiceQuery {
+	q.Categories = append(q.Categories, d.Categories...)
+	q.Personalities = append(q.Personalities, d.Personalities...)
+	if d.Gender != "" {
+		q.Gender = d.Gender
+	}
+	if d.Locale != "" {
+		q.Locale = d.Locale
+	}
+	if d.Status != "" {
+		q.Status = d.Status
+	}
+	q.Multilingual = q.Multilingual || d.Multilingual
+	return q
+}
+
+// lookupFold 在 values 中查找与 s 相同（不区分大小写）的值
+func lookupFold(values []string, s string) (string, bool) {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,196 @@
+package edgetts_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/BlakeLiAFK/edge-tts/pkg/edgetts"
+)
+
+// queryVoices 查询测试使用的语音列表
+func queryVoices() []edgetts.Voice {
+	voice := func(shortName, gender, status string, categories, personalities []string) edgetts.Voice {
+		return edgetts.Voice{
+			ShortName:    shortName,
+			FriendlyName: "Microsoft " + shortName,
+			Gender:       gender,
+			Locale:       shortName[:5],
+			Status:       status,
+			VoiceTag:     edgetts.VoiceTag{ContentCategories: categories, VoicePersonalities: personalities},
+		}
+	}
+	general, friendly := []string{"General"}, []string{"Friendly", "Positive"}
+	return []edgetts.Voice{
+		voice("en-GB-RyanNeural", "Male", "GA", general, friendly),
+		voice("en-GB-SoniaNeural", "Female", "GA", general, friendly),
+		voice("en-US-EmmaMultilingualNeural", "Female", "GA", []string{"Conversation", "Copilot"}, []string{"Cheerful", "Clear"}),
+		voice("en-US-EmmaNeural", "Female", "GA", []string{"Conversation", "Copilot"}, []string{"Cheerful", "Clear"}),
+		voice("en-US-GuyNeural", "Male", "GA", []string{"News", "Novel"}, []string{"Passion"}),
+		voice("zh-CN-XiaoxiaoNeural", "Female", "GA", []string{"News", "Novel"}, []string{"Warm"}),
+		voice("zh-CN-XiaoyiNeural", "Female", "Preview", []string{"Cartoon", "Novel"}, []string{"Lively"}),
+		voice("zh-TW-HsiaoChenNeural", "Female", "GA", general, friendly),
+	}
+}
+
+func newQueryManager(t *testing.T) *edgetts.VoicesManager {
+	t.Helper()
+	vm := edgetts.NewVoicesManager()
+	if err := vm.Create(context.Background(), queryVoices()); err != nil {
+		t.Fatal(err)
+	}
+	return vm
+}
+
+func shortNames(voices []edgetts.Voice) []string {
+	names := make([]string, len(voices))
+	for i, v := range voices {
+		names[i] = v.ShortName
+	}
+	return names
+}
+
+func TestVoicesManagerQuery(t *testing.T) {
+	vm := newQueryManager(t)
+	tests := []struct {
+		name  string
+		query edgetts.VoiceQuery
+		want  []string
+	}{
+		{"tags", edgetts.VoiceQuery{Categories: []string{"novel"}, Personalities: []string{"WARM"}}, []string{"zh-CN-XiaoxiaoNeural"}},
+		{"multilingual", edgetts.VoiceQuery{Multilingual: true}, []string{"en-US-EmmaMultilingualNeural"}},
+		{"status", edgetts.VoiceQuery{Status: "preview"}, []string{"zh-CN-XiaoyiNeural"}},
+		{"locale", edgetts.VoiceQuery{Locale: "en-gb", Gender: "male"}, []string{"en-GB-RyanNeural"}},
+		// zh-HK 没有语音时放宽为 zh，再放宽为任意地区
+		{"language fallback", edgetts.VoiceQuery{Locale: "zh-HK", Status: "GA"}, []string{"zh-CN-XiaoxiaoNeural", "zh-TW-HsiaoChenNeural"}},
+		{"any fallback", edgetts.VoiceQuery{Locale: "zh-HK", Gender: "Male"}, []string{"en-GB-RyanNeural", "en-US-GuyNeural"}},
+		// 先在地区内放宽标签，包含更多标签的排在前面，再放宽地区
+		{"tags before locale", edgetts.VoiceQuery{Locale: "zh-CN", Categories: []string{"News", "Cartoon"}, Personalities: []string{"Passion"}}, []string{"zh-CN-XiaoxiaoNeural", "zh-CN-XiaoyiNeural"}},
+		{"keywords", edgetts.VoiceQuery{Locale: "en-GB", Keywords: []string{"calm", "sonia"}}, []string{"en-GB-SoniaNeural", "en-GB-RyanNeural"}},
+		{"keyword tags", edgetts.VoiceQuery{Locale: "zh-CN", Keywords: []string{"lively"}}, []string{"zh-CN-XiaoyiNeural", "zh-CN-XiaoxiaoNeural"}},
+		// 完全相同 > 前缀 > 子串 > 拼写相近
+		{"name ranking", edgetts.VoiceQuery{Name: "Emma"}, []string{"en-US-EmmaNeural", "en-US-EmmaMultilingualNeural"}},
+		{"name prefix", edgetts.VoiceQuery{Name: "xiao"}, []string{"zh-CN-XiaoxiaoNeural", "zh-CN-XiaoyiNeural"}},
+		{"name typo", edgetts.VoiceQuery{Name: "sonja"}, []string{"en-GB-SoniaNeural"}},
+		{"name terms", edgetts.VoiceQuery{Name: "emma multilingual"}, []string{"en-US-EmmaMultilingualNeural"}},
+		{"no match", edgetts.VoiceQuery{Name: "zzz"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := vm.Query(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := shortNames(got); !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("Query(%+v) = %v, want %v", tt.query, names, tt.want)
+			}
+		})
+	}
+
+	if _, err := edgetts.NewVoicesManager().Query(edgetts.VoiceQuery{}); err == nil {
+		t.Error("expected error before Create")
+	}
+}
+
+func TestVoicesManagerPick(t *testing.T) {
+	vm := newQueryManager(t)
+
+	// 相同的 seed 总是得到同一个语音，与列表顺序无关
+	q := edgetts.VoiceQuery{Gender: "Female"}
+	first, err := vm.Pick(q, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reversed := queryVoices()
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	other := edgetts.NewVoicesManager()
+	other.Create(context.Background(), reversed)
+	if again, _ := other.Pick(q, 42); again.ShortName != first.ShortName {
+		t.Errorf("Pick = %s, then %s", first.ShortName, again.ShortName)
+	}
+
+	seen := map[string]bool{}
+	for seed := int64(0); seed < 50; seed++ {
+		v, err := vm.Pick(q, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[v.ShortName] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Pick chose %v across seeds, want several voices", seen)
+	}
+
+	// 只在匹配程度最高的语音中选择
+	if v, _ := vm.Pick(edgetts.VoiceQuery{Name: "emma"}, 7); v.ShortName != "en-US-EmmaNeural" {
+		t.Errorf("Pick(emma) = %s", v.ShortName)
+	}
+	if _, err := vm.Pick(edgetts.VoiceQuery{Name: "zzz"}, 0); !errors.Is(err, edgetts.ErrNoVoiceMatch) {
+		t.Errorf("err = %v, want ErrNoVoiceMatch", err)
+	}
+}
+
+func TestParseVoiceQuery(t *testing.T) {
+	tests := []struct {
+		description string
+		want        edgetts.VoiceQuery
+	}{
+		{"cheerful female British", edgetts.VoiceQuery{Gender: "Female", Locale: "en-GB", Personalities: []string{"Cheerful"}}},
+		{"a warm male voice, zh-cn, news", edgetts.VoiceQuery{Gender: "Male", Locale: "zh-CN", Categories: []string{"News"}, Personalities: []string{"Warm"}}},
+		{"Multilingual Emma preview", edgetts.VoiceQuery{Keywords: []string{"emma"}, Multilingual: true, Status: "Preview"}},
+		{"calm female narrator", edgetts.VoiceQuery{Keywords: []string{"calm"}, Gender: "Female", Categories: []string{"Novel"}}},
+	}
+	for _, tt := range tests {
+		if got := edgetts.ParseVoiceQuery(tt.description); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseVoiceQuery(%q) = %+v, want %+v", tt.description, got, tt.want)
+		}
+	}
+
+	// en-GB 没有 cheerful 的女声，在 en-GB 内放宽声音特点，不放宽为其他英语地区
+	v, err := newQueryManager(t).Pick(edgetts.ParseVoiceQuery("cheerful female British"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ShortName != "en-GB-SoniaNeural" {
+		t.Errorf("Pick = %s", v.ShortName)
+	}
+}
+
+func TestPickDescriptionsFromSnapshot(t *testing.T) {
+	vm := edgetts.NewVoicesManager()
+	if err := vm.Create(context.Background(), edgetts.SnapshotVoices()); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		description    string
+		gender, locale string
+	}{
+		{"cheerful female British", "Female", "en-GB"},
+		{"calm female British", "Female", "en-GB"},
+		{"young male american", "Male", "en-US"},
+		{"female narrator", "Female", ""},
+		{"deep male voice", "Male", ""},
+		{"warm female Chinese", "Female", "zh-"},
+	}
+	for _, tt := range tests {
+		v, err := vm.Pick(edgetts.ParseVoiceQuery(tt.description), 1)
+		if err != nil {
+			t.Errorf("Pick(%q): %v", tt.description, err)
+			continue
+		}
+		if v.Gender != tt.gender || !strings.HasPrefix(v.Locale, tt.locale) {
+			t.Errorf("Pick(%q) = %s (%s)", tt.description, v.ShortName, v.Gender)
+		}
+	}
+
+	// narrator 优先选择 Novel 类别的语音
+	v, _ := vm.Pick(edgetts.ParseVoiceQuery("female narrator"), 1)
+	if !slices.Contains(v.VoiceTag.ContentCategories, "Novel") {
+		t.Errorf("Pick(female narrator) = %s %v", v.ShortName, v.VoiceTag.ContentCategories)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -12,62 +13,91 @@ import (
 
 // ListVoicesOptions 列出语音的选项
 type ListVoicesOptions struct {
-	Proxy   string
+	Proxy   string // 代理地址，格式同 WithProxy；为空时读取环境变量
 	Timeout time.Duration
+	Client  *Client      // 传输层客户端，为空时使用 DefaultClient
+	Retry   *RetryPolicy // 重试策略，为空时使用 DefaultRetryPolicy
+	Logger  *slog.Logger // 日志记录器，为空时不输出日志
 }
 
-// listVoicesInternal 内部函数，执行实际的语音列表请求
-func listVoicesInternal(ctx context.Context, opts *ListVoicesOptions) ([]Voice, error) {
-	drm := GetDRM()
+// voiceList 语音列表和用于条件请求的校验信息，也是磁盘缓存的文件格式
+type voiceList struct {
+	FetchedAt    time.Time `json:"fetched_at"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Voices       []Voice   `json:"voices"`
+}
 
-	url := fmt.Sprintf("%s&Sec-MS-GEC=%s&Sec-MS-GEC-Version=%s",
-		VoiceList, drm.GenerateSecMSGEC(), SecMSGECVersion)
+// listVoicesInternal 内部函数，执行实际的语音列表请求
+// prev 不为空时带 If-None-Match/If-Modified-Since 发送条件请求，服务端返回 304 时 notModified 为 true
+func listVoicesInternal(ctx context.Context, opts *ListVoicesOptions, prev *voiceList) (list *voiceList, notModified bool, err error) {
+	cl := opts.Client.orDefault()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	url, err := cl.voiceListURL()
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	// 设置 headers
-	headers := HeadersWithMUID(VoiceHeaders)
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
+	// 自定义 HTTPClient 不一定设置了超时，统一由 context 控制
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
 
-	client := &http.Client{
-		Timeout: opts.Timeout,
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header = cl.voiceListHeaders()
+	if prev != nil {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
+		}
 	}
+	logger := loggerOrDiscard(opts.Logger)
+	logger.Debug("requesting voice list", "url", redactURL(url), "conditional", prev != nil)
 
-	// 设置代理
-	if opts.Proxy != "" {
-		// 简化处理，实际使用时需要配置代理
+	client, err := cl.httpClient(opts.Proxy, opts.Timeout)
+	if err != nil {
+		return nil, false, err
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == 403 {
-		return nil, fmt.Errorf("forbidden: status %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		logger.Debug("voice list not modified")
+		return prev, true, nil
 	}
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, newHandshakeError(opListVoices, resp, nil)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	var voices []Voice
 	if err := json.Unmarshal(body, &voices); err != nil {
-		return nil, err
+		return nil, false, err
 	}
+	logger.Debug("received voice list", "voices", len(voices), "bytes", len(body))
 
-	// 确保 VoiceTag 字段存在
+	return &voiceList{
+		FetchedAt:    time.Now(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Voices:       normalizeVoices(voices),
+	}, false, nil
+}
+
+// normalizeVoices 确保 VoiceTag 字段存在
+func normalizeVoices(voices []Voice) []Voice {
 	for i := range voices {
 		if voices[i].VoiceTag.ContentCategories == nil {
 			voices[i].VoiceTag.ContentCategories = []string{}
@@ -76,12 +106,20 @@ func listVoicesInternal(ctx context.Context, opts *ListVoicesOptions) ([]Voice,
 			voices[i].VoiceTag.VoicePersonalities = []string{}
 		}
 	}
-
-	return voices, nil
+	return voices
 }
 
 // ListVoices 列出所有可用的语音
 func ListVoices(ctx context.Context, opts *ListVoicesOptions) ([]Voice, error) {
+	list, _, err := fetchVoiceList(ctx, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	return list.Voices, nil
+}
+
+// fetchVoiceList 按重试策略获取语音列表，prev 见 listVoicesInternal
+func fetchVoiceList(ctx context.Context, opts *ListVoicesOptions, prev *voiceList) (*voiceList, bool, error) {
 	if opts == nil {
 		opts = &ListVoicesOptions{
 			Timeout: 30 * time.Second,
@@ -91,22 +129,43 @@ func ListVoices(ctx context.Context, opts *ListVoicesOptions) ([]Voice, error) {
 		opts.Timeout = 30 * time.Second
 	}
 
-	voices, err := listVoicesInternal(ctx, opts)
+	policy := DefaultRetryPolicy
+	if opts.Retry != nil {
+		policy = *opts.Retry
+	}
+
+	// 403 时根据服务端 Date 头校正时钟偏移后重试
+	var list *voiceList
+	var notModified bool
+	onRetry := func(n int, err error, wait time.Duration) {
+		loggerOrDiscard(opts.Logger).Warn("retrying voice list request", "attempt", n+1, "wait", wait, "err", err)
+	}
+	err := policy.do(ctx, opts.Client.orDefault().drm(), onRetry, func() error {
+		var err error
+		list, notModified, err = listVoicesInternal(ctx, opts, prev)
+		return err
+	})
 	if err != nil {
-		// 如果是 403 错误，尝试调整时钟偏移后重试
-		if strings.Contains(err.Error(), "forbidden") || strings.Contains(err.Error(), "403") {
-			// 注意：这里简化处理，实际上应该从响应中获取服务器时间
-			return listVoicesInternal(ctx, opts)
-		}
-		return nil, err
+		return nil, false, err
 	}
-	return voices, nil
+	return list, notModified, nil
 }
 
 // VoicesManager 语音管理器
 type VoicesManager struct {
 	Voices       []Voice
 	CalledCreate bool
+	ListOptions  *ListVoicesOptions // Create 联网获取语音列表时使用的选项
+	// Cache 磁盘缓存，为 nil 时每次 Create 都联网获取
+	Cache *VoiceCache
+	// VoicesFile 自定义的语音列表文件，格式同 ListVoices 的返回值；设置后不联网
+	VoicesFile string
+	// SnapshotFallback 联网失败且没有缓存时使用 SnapshotVoices 返回的快照
+	SnapshotFallback bool
+	// Source Create 得到的语音列表的来源
+	Source VoiceSource
+	// UpdatedAt 语音列表从服务端获取或确认的时间，来自文件、快照或调用方时为零值
+	UpdatedAt time.Time
 }
 
 // NewVoicesManager 创建新的 VoicesManager
@@ -118,15 +177,23 @@ func NewVoicesManager() *VoicesManager {
 }
 
 // Create 创建并填充 VoicesManager
+// customVoices 不为 nil 时直接使用；否则依次尝试 VoicesFile、有效的磁盘缓存、服务端、过期的磁盘缓存和快照
 func (vm *VoicesManager) Create(ctx context.Context, customVoices []Voice) error {
 	var voices []Voice
+	var source VoiceSource
+	var updated time.Time
 	var err error
 
-	if customVoices != nil {
-		voices = customVoices
-	} else {
-		voices, err = ListVoices(ctx, nil)
-		if err != nil {
+	switch {
+	case customVoices != nil:
+		voices, source = customVoices, VoiceSourceCustom
+	case vm.VoicesFile != "":
+		if voices, err = LoadVoicesFile(vm.VoicesFile); err != nil {
+			return err
+		}
+		source = VoiceSourceFile
+	default:
+		if voices, source, updated, err = vm.load(ctx); err != nil {
 			return err
 		}
 	}
@@ -140,10 +207,54 @@ func (vm *VoicesManager) Create(ctx context.Context, customVoices []Voice) error
 	}
 
 	vm.Voices = voices
+	vm.Source, vm.UpdatedAt = source, updated
 	vm.CalledCreate = true
 	return nil
 }
 
+// load 从磁盘缓存、服务端或快照获取语音列表，返回来源和获取时间
+func (vm *VoicesManager) load(ctx context.Context) ([]Voice, VoiceSource, time.Time, error) {
+	var logger *slog.Logger
+	if vm.ListOptions != nil {
+		logger = vm.ListOptions.Logger
+	}
+	logger = loggerOrDiscard(logger)
+
+	var cached *voiceList
+	if vm.Cache != nil {
+		var err error
+		if cached, err = vm.Cache.read(); err != nil {
+			logger.Warn("ignoring voice cache", "err", err)
+		}
+		if cached != nil && time.Since(cached.FetchedAt) < vm.Cache.ttl() {
+			return cached.Voices, VoiceSourceCache, cached.FetchedAt, nil
+		}
+	}
+
+	list, notModified, err := fetchVoiceList(ctx, vm.ListOptions, cached)
+	switch {
+	case err == nil:
+		source := VoiceSourceNetwork
+		if notModified {
+			source = VoiceSourceCache
+			list.FetchedAt = time.Now()
+		}
+		if vm.Cache != nil {
+			if err := vm.Cache.write(list); err != nil {
+				logger.Warn("failed to write voice cache", "err", err)
+			}
+		}
+		return list.Voices, source, list.FetchedAt, nil
+	case cached != nil:
+		logger.Warn("failed to refresh voice list, using stale cache", "fetched_at", cached.FetchedAt, "err", err)
+		return cached.Voices, VoiceSourceCache, cached.FetchedAt, nil
+	case vm.SnapshotFallback && ctx.Err() == nil:
+		logger.Warn("failed to fetch voice list, using the built-in partial snapshot", "err", err)
+		return SnapshotVoices(), VoiceSourceSnapshot, time.Time{}, nil
+	}
+	return nil, "", time.Time{}, err
+}
+
 // Find 根据条件查找语音
 func (vm *VoicesManager) Find(gender, locale, language string) ([]Voice, error) {
 	if !vm.CalledCreate {
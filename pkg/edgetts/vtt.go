@@ -0,0 +1,37 @@
+package edgetts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ToVTT 将字幕转换为 WebVTT 格式块，说话人输出为 <v Speaker> 标签
+func (s *Subtitle) ToVTT() string {
+	content := escapeVTT(makeLegalContent(s.Content))
+	if s.Speaker != "" {
+		content = "<v " + escapeVTT(s.Speaker) + ">" + content
+	}
+	return fmt.Sprintf("%s --> %s\n%s\n\n",
+		timeDurationToVTTTimestamp(s.Start), timeDurationToVTTTimestamp(s.End), content)
+}
+
+// escapeVTT 转义 WebVTT 文本中的特殊字符
+func escapeVTT(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// timeDurationToVTTTimestamp 将 time.Duration 转换为 WebVTT 时间戳
+func timeDurationToVTTTimestamp(d time.Duration) string {
+	return strings.Replace(timeDurationToSRTTimestamp(d), ",", ".", 1)
+}
+
+// ComposeVTT 组合字幕为 WebVTT 字符串
+func ComposeVTT(subtitles []Subtitle) string {
+	var builder strings.Builder
+	builder.WriteString("WEBVTT\n\n")
+	for _, sub := range sortAndReindex(subtitles, 1, true) {
+		builder.WriteString(sub.ToVTT())
+	}
+	return builder.String()
+}